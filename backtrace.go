@@ -0,0 +1,99 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"github.com/dunglas/frankenphp/internal/state"
+)
+
+var (
+	ErrThreadNotFound   = errors.New("thread not found")
+	ErrThreadNotBusy    = errors.New("thread is not handling a request")
+	ErrBacktraceTimeout = errors.New("thread did not reach a VM interrupt point in time, it may be blocked in a system call")
+)
+
+// EXPERIMENTAL: StackFrame is a frame of the PHP call stack of a thread - debugging purposes only
+type StackFrame struct {
+	Class    string
+	Function string
+	File     string
+	Line     int
+}
+
+func (f StackFrame) String() string {
+	function := f.Function
+	if f.Class != "" {
+		function = f.Class + "::" + f.Function
+	}
+
+	if f.File == "" {
+		return function + "()"
+	}
+
+	return fmt.Sprintf("%s() %s:%d", function, f.File, f.Line)
+}
+
+// EXPERIMENTAL: ThreadBacktrace returns the PHP call stack of the request running on the thread with the given index,
+// innermost frame first. The stack is captured by the thread itself at the next VM interrupt point,
+// threads blocked in a system call (sleep, network I/O...) cannot report it before the timeout.
+func ThreadBacktrace(index int, timeout time.Duration) ([]StackFrame, error) {
+	if index < 0 || index >= len(phpThreads) {
+		return nil, ErrThreadNotFound
+	}
+
+	return phpThreads[index].backtrace(timeout)
+}
+
+func (thread *phpThread) backtrace(timeout time.Duration) ([]StackFrame, error) {
+	if !thread.state.Is(state.Ready) || thread.state.IsInWaitingState() {
+		return nil, ErrThreadNotBusy
+	}
+
+	// only one backtrace can be requested at a time per thread
+	thread.backtraceMu.Lock()
+	defer thread.backtraceMu.Unlock()
+
+	ch := make(chan []StackFrame, 1)
+	thread.backtraceChan.Store(&ch)
+	defer thread.backtraceChan.Store(nil)
+
+	thread.forceKillMu.RLock()
+	C.frankenphp_request_backtrace(thread.forceKill, C.uintptr_t(thread.threadIndex))
+	thread.forceKillMu.RUnlock()
+
+	select {
+	case frames := <-ch:
+		return frames, nil
+	case <-time.After(timeout):
+		return nil, ErrBacktraceTimeout
+	}
+}
+
+//export go_frankenphp_report_backtrace
+func go_frankenphp_report_backtrace(threadIndex C.uintptr_t, frames *C.frankenphp_stack_frame, count C.int) {
+	ch := phpThreads[threadIndex].backtraceChan.Load()
+	if ch == nil {
+		// nobody is waiting anymore
+		return
+	}
+
+	stack := make([]StackFrame, 0, int(count))
+	for _, f := range unsafe.Slice(frames, int(count)) {
+		stack = append(stack, StackFrame{
+			Class:    C.GoStringN(f.class_name, C.int(f.class_name_len)),
+			Function: C.GoStringN(f.function, C.int(f.function_len)),
+			File:     C.GoStringN(f.file, C.int(f.file_len)),
+			Line:     int(f.line),
+		})
+	}
+
+	select {
+	case *ch <- stack:
+	default:
+	}
+}
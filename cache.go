@@ -0,0 +1,262 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// defaultCacheMaxSize is the default memory limit of the shared cache, like the default shm_size of APCu
+const defaultCacheMaxSize = 32 << 20
+
+// estimated memory used by an entry besides its key and value
+const cacheEntryOverhead = 64
+
+var (
+	// nil if FrankenPHP is not running
+	sharedCache *cache
+
+	errCacheNotInteger = errors.New("the cached value is not an integer")
+)
+
+// cache is a key/value store shared by all PHP threads, the least recently used entries are evicted
+// when the estimated size of the entries exceeds maxSize
+type cache struct {
+	mu      sync.Mutex
+	maxSize int64
+	size    int64
+	entries map[string]*list.Element
+	// most recently used first
+	lru *list.List
+	// replaced in tests
+	clock clock
+}
+
+type cacheEntry struct {
+	key   string
+	value any
+	size  int64
+	// zero if the entry never expires
+	expiresAt time.Time
+}
+
+func newCache(maxSize int64) *cache {
+	return &cache{
+		maxSize: maxSize,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		clock:   realClock{},
+	}
+}
+
+func (c *cache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.lookup(key)
+	if e == nil {
+		metrics.CacheMiss()
+
+		return nil, false
+	}
+
+	metrics.CacheHit()
+
+	return e.Value.(*cacheEntry).value, true
+}
+
+// set stores the value, it returns false if the value alone exceeds the size of the cache
+func (c *cache) set(key string, value any, ttl time.Duration) bool {
+	entry := &cacheEntry{
+		key:   key,
+		value: value,
+		size:  cacheEntryOverhead + int64(len(key)) + cacheValueSize(value),
+	}
+	if ttl > 0 {
+		entry.expiresAt = c.clock.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxSize > 0 && entry.size > c.maxSize {
+		return false
+	}
+
+	c.store(entry)
+
+	return true
+}
+
+func (c *cache) delete(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.lookup(key)
+	if e == nil {
+		return false
+	}
+
+	c.remove(e)
+
+	return true
+}
+
+// increment adds step to the integer stored at key, the entry is created with the given ttl if it doesn't exist
+func (c *cache) increment(key string, step int64, ttl time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e := c.lookup(key); e != nil {
+		entry := e.Value.(*cacheEntry)
+
+		v, ok := entry.value.(int64)
+		if !ok {
+			return 0, fmt.Errorf("%w: %q", errCacheNotInteger, key)
+		}
+
+		entry.value = v + step
+
+		return v + step, nil
+	}
+
+	entry := &cacheEntry{
+		key:   key,
+		value: step,
+		size:  cacheEntryOverhead + int64(len(key)) + cacheValueSize(step),
+	}
+	if ttl > 0 {
+		entry.expiresAt = c.clock.Now().Add(ttl)
+	}
+
+	c.store(entry)
+
+	return step, nil
+}
+
+// lookup returns the element of the key and marks it as recently used, expired entries are removed.
+// Must be called with mu held.
+func (c *cache) lookup(key string) *list.Element {
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	if expiresAt := e.Value.(*cacheEntry).expiresAt; !expiresAt.IsZero() && c.clock.Now().After(expiresAt) {
+		c.remove(e)
+
+		return nil
+	}
+
+	c.lru.MoveToFront(e)
+
+	return e
+}
+
+// store inserts or replaces the entry and evicts the least recently used entries if needed.
+// Must be called with mu held.
+func (c *cache) store(entry *cacheEntry) {
+	if e, ok := c.entries[entry.key]; ok {
+		c.remove(e)
+	}
+
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.size += entry.size
+
+	for c.maxSize > 0 && c.size > c.maxSize {
+		c.remove(c.lru.Back())
+		metrics.CacheEviction()
+	}
+}
+
+// remove must be called with mu held
+func (c *cache) remove(e *list.Element) {
+	entry := c.lru.Remove(e).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+}
+
+// cacheValueSize estimates the memory used by a value converted by goValue()
+func cacheValueSize(value any) int64 {
+	switch v := value.(type) {
+	case string:
+		return 16 + int64(len(v))
+	case []any:
+		size := int64(24)
+		for _, item := range v {
+			size += cacheValueSize(item)
+		}
+
+		return size
+	case AssociativeArray[any]:
+		size := int64(48)
+		for k, item := range v.Map {
+			size += 32 + int64(len(k)) + cacheValueSize(item)
+		}
+
+		return size
+	default:
+		return 16
+	}
+}
+
+func cacheTTL(ttl C.zend_long) time.Duration {
+	return time.Duration(ttl) * time.Second
+}
+
+//export go_frankenphp_cache_get
+func go_frankenphp_cache_get(key *C.zend_string) (*C.zval, C.bool) {
+	if sharedCache == nil {
+		return nil, false
+	}
+
+	v, ok := sharedCache.get(GoString(unsafe.Pointer(key)))
+	if !ok {
+		return nil, false
+	}
+
+	return phpValue(v), true
+}
+
+//export go_frankenphp_cache_set
+func go_frankenphp_cache_set(key *C.zend_string, value *C.zval, ttl C.zend_long) (C.bool, *C.char) {
+	if sharedCache == nil {
+		return false, nil
+	}
+
+	v, err := goValue[any](value)
+	if err != nil {
+		// PHP exception message.
+		return false, C.CString("unable to convert the value: " + err.Error())
+	}
+
+	return C.bool(sharedCache.set(GoString(unsafe.Pointer(key)), v, cacheTTL(ttl))), nil
+}
+
+//export go_frankenphp_cache_delete
+func go_frankenphp_cache_delete(key *C.zend_string) C.bool {
+	if sharedCache == nil {
+		return false
+	}
+
+	return C.bool(sharedCache.delete(GoString(unsafe.Pointer(key))))
+}
+
+//export go_frankenphp_cache_increment
+func go_frankenphp_cache_increment(key *C.zend_string, step C.zend_long, ttl C.zend_long) (C.zend_long, *C.char) {
+	if sharedCache == nil {
+		return 0, C.CString("the cache is not available")
+	}
+
+	v, err := sharedCache.increment(GoString(unsafe.Pointer(key)), int64(step), cacheTTL(ttl))
+	if err != nil {
+		return 0, C.CString(err.Error())
+	}
+
+	return C.zend_long(v), nil
+}
@@ -0,0 +1,67 @@
+package frankenphp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheEvictsLeastRecentlyUsedEntries(t *testing.T) {
+	entrySize := cacheEntryOverhead + 1 + cacheValueSize("value")
+	c := newCache(3 * entrySize)
+
+	require.True(t, c.set("a", "value", 0))
+	require.True(t, c.set("b", "value", 0))
+	require.True(t, c.set("c", "value", 0))
+
+	// "a" becomes the most recently used entry
+	_, ok := c.get("a")
+	require.True(t, ok)
+
+	require.True(t, c.set("d", "value", 0))
+
+	_, ok = c.get("b")
+	assert.False(t, ok, "the least recently used entry must be evicted")
+	for _, key := range []string{"a", "c", "d"} {
+		_, ok = c.get(key)
+		assert.True(t, ok, key)
+	}
+	assert.Equal(t, 3*entrySize, c.size)
+
+	assert.False(t, c.set("too-large", string(make([]byte, 4*entrySize)), 0))
+}
+
+func TestCacheTTL(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, time.January, 15, 10, 30, 20, 0, time.UTC)}
+	c := newCache(defaultCacheMaxSize)
+	c.clock = clock
+
+	require.True(t, c.set("key", int64(42), time.Minute))
+
+	clock.advance(time.Minute - time.Second)
+	_, ok := c.get("key")
+	assert.True(t, ok)
+
+	clock.advance(2 * time.Second)
+	_, ok = c.get("key")
+	assert.False(t, ok)
+	assert.Zero(t, c.size)
+}
+
+func TestCacheIncrement(t *testing.T) {
+	c := newCache(defaultCacheMaxSize)
+
+	v, err := c.increment("counter", 2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), v)
+
+	v, err = c.increment("counter", -5, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-3), v)
+
+	c.set("string", "value", 0)
+	_, err = c.increment("string", 1, 0)
+	assert.ErrorIs(t, err, errCacheNotInteger)
+}
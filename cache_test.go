@@ -0,0 +1,20 @@
+package frankenphp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, _ int) {
+		body, _ := testGet("http://example.com/cache.php", handler, t)
+		assert.Equal(t, `["default",true,{"debug":true,"hosts":["a","b"]},1,11,true,false,null,"the cached value is not an integer: \"name\""]`, body)
+
+		// the values are shared by all threads and requests
+		body, _ = testGet("http://example.com/cache.php", handler, t)
+		assert.Equal(t, `["default",true,{"debug":true,"hosts":["a","b"]},12,22,true,false,null,"the cached value is not an integer: \"name\""]`, body)
+	}, &testOptions{nbParallelRequests: 1})
+}
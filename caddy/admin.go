@@ -2,13 +2,22 @@ package caddy
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/caddyserver/caddy/v2"
 	"github.com/dunglas/frankenphp"
 )
 
+// max time to wait for a thread to report its backtrace
+const backtraceTimeout = 2 * time.Second
+
 type FrankenPHPAdmin struct {
 }
 
@@ -27,10 +36,18 @@ func (admin FrankenPHPAdmin) Routes() []caddy.AdminRoute {
 			Pattern: "/frankenphp/workers/restart",
 			Handler: caddy.AdminHandlerFunc(admin.restartWorkers),
 		},
+		{
+			Pattern: "/frankenphp/workers/",
+			Handler: caddy.AdminHandlerFunc(admin.worker),
+		},
 		{
 			Pattern: "/frankenphp/threads",
 			Handler: caddy.AdminHandlerFunc(admin.threads),
 		},
+		{
+			Pattern: "/frankenphp/threads/",
+			Handler: caddy.AdminHandlerFunc(admin.threadBacktrace),
+		},
 	}
 }
 
@@ -39,14 +56,110 @@ func (admin *FrankenPHPAdmin) restartWorkers(w http.ResponseWriter, r *http.Requ
 		return admin.error(http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 	}
 
-	frankenphp.RestartWorkers()
-	caddy.Log().Info("workers restarted from admin api")
+	strategy, err := restartStrategyFromQuery(r)
+	if err != nil {
+		return admin.error(http.StatusBadRequest, err)
+	}
+
+	frankenphp.RestartWorkersWithStrategy(strategy)
+	caddy.Log().Sugar().Infow("workers restarted from admin api", "strategy", strategy.String())
 
 	return admin.success(w, "workers restarted successfully\n")
 }
 
-func (admin *FrankenPHPAdmin) threads(w http.ResponseWriter, _ *http.Request) error {
-	debugState := frankenphp.DebugState()
+// worker handles the routes of a single worker, the name may be URL-encoded:
+// POST /frankenphp/workers/{name}/restart and PATCH /frankenphp/workers/{name}
+func (admin *FrankenPHPAdmin) worker(w http.ResponseWriter, r *http.Request) error {
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/frankenphp/workers/")
+	escapedName, restart := strings.CutSuffix(path, "/restart")
+	if escapedName == "" {
+		return admin.error(http.StatusNotFound, fmt.Errorf("not found"))
+	}
+
+	name, err := url.PathUnescape(escapedName)
+	if err != nil {
+		return admin.error(http.StatusBadRequest, err)
+	}
+
+	if restart {
+		return admin.restartWorker(w, r, name)
+	}
+
+	return admin.updateWorker(w, r, name)
+}
+
+func (admin *FrankenPHPAdmin) restartWorker(w http.ResponseWriter, r *http.Request, name string) error {
+	if r.Method != http.MethodPost {
+		return admin.error(http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+	}
+
+	var err error
+	graceful := true
+	if v := r.URL.Query().Get("graceful"); v != "" {
+		if graceful, err = strconv.ParseBool(v); err != nil {
+			return admin.error(http.StatusBadRequest, fmt.Errorf("invalid graceful parameter: %w", err))
+		}
+	}
+
+	strategy, err := restartStrategyFromQuery(r)
+	if err != nil {
+		return admin.error(http.StatusBadRequest, err)
+	}
+
+	err = frankenphp.RestartWorkerWithStrategy(name, strategy, !graceful)
+	if errors.Is(err, frankenphp.ErrWorkerNotFound) {
+		return admin.error(http.StatusNotFound, err)
+	}
+	if err != nil {
+		return admin.error(http.StatusInternalServerError, err)
+	}
+
+	caddy.Log().Sugar().Infow("worker restarted from admin api", "worker", name, "graceful", graceful, "strategy", strategy.String())
+
+	return admin.success(w, fmt.Sprintf("worker %q restarted successfully\n", name))
+}
+
+// restartStrategyFromQuery reads the optional ?strategy=all|rolling parameter
+func restartStrategyFromQuery(r *http.Request) (frankenphp.RestartStrategy, error) {
+	v := r.URL.Query().Get("strategy")
+	if v == "" {
+		return frankenphp.RestartStrategyDefault, nil
+	}
+
+	return parseRestartStrategy(v)
+}
+
+// updateWorker changes the number of threads of a worker, the body is a JSON object like {"num": 4}
+func (admin *FrankenPHPAdmin) updateWorker(w http.ResponseWriter, r *http.Request, name string) error {
+	if r.Method != http.MethodPatch {
+		return admin.error(http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+	}
+
+	var body struct {
+		Num *int `json:"num"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return admin.error(http.StatusBadRequest, fmt.Errorf("invalid JSON body: %w", err))
+	}
+	if body.Num == nil {
+		return admin.error(http.StatusBadRequest, fmt.Errorf(`the "num" field is required`))
+	}
+
+	if err := frankenphp.SetWorkerThreads(name, *body.Num); err != nil {
+		return admin.error(resizeErrorStatus(err), err)
+	}
+
+	caddy.Log().Sugar().Infow("worker threads changed from admin api", "worker", name, "num", *body.Num)
+
+	return admin.success(w, fmt.Sprintf("worker %q updated successfully\n", name))
+}
+
+func (admin *FrankenPHPAdmin) threads(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == http.MethodPatch {
+		return admin.updateThreads(w, r)
+	}
+
+	debugState := filterDebugState(frankenphp.DebugState(), r.URL.Query())
 	prettyJson, err := json.MarshalIndent(debugState, "", "    ")
 	if err != nil {
 		return admin.error(http.StatusInternalServerError, err)
@@ -55,6 +168,115 @@ func (admin *FrankenPHPAdmin) threads(w http.ResponseWriter, _ *http.Request) er
 	return admin.success(w, string(prettyJson))
 }
 
+// filterDebugState applies the ?worker=, ?server= and ?state= filters,
+// state is either "busy", "idle" or the name of a thread state like "ready"
+func filterDebugState(debugState frankenphp.FrankenPHPDebugState, query url.Values) frankenphp.FrankenPHPDebugState {
+	worker, server, threadState := query.Get("worker"), query.Get("server"), query.Get("state")
+
+	debugState.ThreadDebugStates = slices.DeleteFunc(debugState.ThreadDebugStates, func(s frankenphp.ThreadDebugState) bool {
+		if worker != "" && s.WorkerName != worker {
+			return true
+		}
+		if server != "" && s.ServerName != server {
+			return true
+		}
+
+		switch threadState {
+		case "":
+			return false
+		case "busy":
+			return !s.IsBusy
+		case "idle":
+			return !s.IsWaiting
+		default:
+			return s.State != threadState
+		}
+	})
+
+	debugState.WorkerDebugStates = slices.DeleteFunc(debugState.WorkerDebugStates, func(s frankenphp.WorkerDebugState) bool {
+		return (worker != "" && s.Name != worker) || (server != "" && s.ServerName != server)
+	})
+
+	debugState.ServerDebugStates = slices.DeleteFunc(debugState.ServerDebugStates, func(s frankenphp.ServerDebugState) bool {
+		return server != "" && s.Name != server
+	})
+
+	return debugState
+}
+
+// threadBacktrace handles GET /frankenphp/threads/{index}/backtrace
+func (admin *FrankenPHPAdmin) threadBacktrace(w http.ResponseWriter, r *http.Request) error {
+	path := strings.TrimPrefix(r.URL.Path, "/frankenphp/threads/")
+	rawIndex, ok := strings.CutSuffix(path, "/backtrace")
+	if !ok {
+		return admin.error(http.StatusNotFound, fmt.Errorf("not found"))
+	}
+
+	if r.Method != http.MethodGet {
+		return admin.error(http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+	}
+
+	index, err := strconv.Atoi(rawIndex)
+	if err != nil {
+		return admin.error(http.StatusNotFound, fmt.Errorf("invalid thread index %q", rawIndex))
+	}
+
+	frames, err := frankenphp.ThreadBacktrace(index, backtraceTimeout)
+	switch {
+	case errors.Is(err, frankenphp.ErrThreadNotFound):
+		return admin.error(http.StatusNotFound, err)
+	case errors.Is(err, frankenphp.ErrThreadNotBusy):
+		return admin.error(http.StatusConflict, err)
+	case errors.Is(err, frankenphp.ErrBacktraceTimeout):
+		return admin.error(http.StatusGatewayTimeout, err)
+	case err != nil:
+		return admin.error(http.StatusInternalServerError, err)
+	}
+
+	prettyJson, err := json.MarshalIndent(struct {
+		Index  int
+		Frames []frankenphp.StackFrame
+	}{index, frames}, "", "    ")
+	if err != nil {
+		return admin.error(http.StatusInternalServerError, err)
+	}
+
+	return admin.success(w, string(prettyJson))
+}
+
+// updateThreads changes the thread limits, the body is a JSON object like {"max_threads": 32}
+func (admin *FrankenPHPAdmin) updateThreads(w http.ResponseWriter, r *http.Request) error {
+	var body struct {
+		MaxThreads *int `json:"max_threads"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return admin.error(http.StatusBadRequest, fmt.Errorf("invalid JSON body: %w", err))
+	}
+	if body.MaxThreads == nil {
+		return admin.error(http.StatusBadRequest, fmt.Errorf(`the "max_threads" field is required`))
+	}
+
+	if err := frankenphp.SetMaxThreads(*body.MaxThreads); err != nil {
+		return admin.error(resizeErrorStatus(err), err)
+	}
+
+	caddy.Log().Sugar().Infow("max_threads changed from admin api", "max_threads", *body.MaxThreads)
+
+	return admin.success(w, "threads updated successfully\n")
+}
+
+// resizeErrorStatus maps the errors returned when resizing thread pools to an HTTP status
+func resizeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, frankenphp.ErrWorkerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, frankenphp.ErrNotStarted), errors.Is(err, frankenphp.ErrNotRunning):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
+
 func (admin *FrankenPHPAdmin) success(w http.ResponseWriter, message string) error {
 	w.WriteHeader(http.StatusOK)
 	_, err := w.Write([]byte(message))
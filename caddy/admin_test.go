@@ -52,6 +52,107 @@ func TestRestartWorkerViaAdminApi(t *testing.T) {
 	tester.AssertGetResponse("http://localhost:"+testPort+"/", http.StatusOK, "requests:1")
 }
 
+func TestRestartSingleWorkerViaAdminApi(t *testing.T) {
+	tester := caddytest.NewTester(t)
+	tester.InitServer(`
+		{
+			skip_install_trust
+			admin localhost:2999
+			http_port `+testPort+`
+
+			frankenphp {
+				worker {
+					name counter
+					file ../testdata/worker-with-counter.php
+					num 1
+				}
+			}
+		}
+
+		localhost:`+testPort+` {
+			route {
+				root ../testdata
+				rewrite worker-with-counter.php
+				php
+			}
+		}
+		`, "caddyfile")
+
+	// make sure workers are not still running from any previous tests
+	assertAdminResponse(t, tester, "POST", "workers/counter/restart", http.StatusOK, "worker \"counter\" restarted successfully\n")
+
+	tester.AssertGetResponse("http://localhost:"+testPort+"/", http.StatusOK, "requests:1")
+	tester.AssertGetResponse("http://localhost:"+testPort+"/", http.StatusOK, "requests:2")
+
+	assertAdminResponse(t, tester, "POST", "workers/counter/restart?graceful=false", http.StatusOK, "worker \"counter\" restarted successfully\n")
+
+	tester.AssertGetResponse("http://localhost:"+testPort+"/", http.StatusOK, "requests:1")
+
+	assertAdminResponse(t, tester, "POST", "workers/counter/restart?strategy=rolling", http.StatusOK, "worker \"counter\" restarted successfully\n")
+
+	tester.AssertGetResponse("http://localhost:"+testPort+"/", http.StatusOK, "requests:1")
+
+	assertAdminResponse(t, tester, "POST", "workers/counter/restart?strategy=blue-green", http.StatusBadRequest, "")
+	assertAdminResponse(t, tester, "POST", "workers/unknown/restart", http.StatusNotFound, "")
+	assertAdminResponse(t, tester, "GET", "workers/counter/restart", http.StatusMethodNotAllowed, "")
+}
+
+func TestResizeThreadPoolsViaAdminApi(t *testing.T) {
+	tester := caddytest.NewTester(t)
+	tester.InitServer(`
+		{
+			skip_install_trust
+			admin localhost:2999
+			http_port `+testPort+`
+
+			frankenphp {
+				num_threads 2
+				max_threads 6
+				worker {
+					name counter
+					file ../testdata/worker-with-counter.php
+					num 1
+				}
+			}
+		}
+
+		localhost:`+testPort+` {
+			route {
+				root ../testdata
+				rewrite worker-with-counter.php
+				php
+			}
+		}
+		`, "caddyfile")
+
+	assertAdminPatch(t, tester, "workers/counter", `{"num": 3}`, http.StatusOK, "worker \"counter\" updated successfully\n")
+	assert.Equal(t, 3, countReadyWorkerThreads(t, tester, "counter"))
+	tester.AssertGetResponse("http://localhost:"+testPort+"/", http.StatusOK, "requests:1")
+
+	assertAdminPatch(t, tester, "workers/counter", `{"num": 1}`, http.StatusOK, "worker \"counter\" updated successfully\n")
+	assert.Equal(t, 1, countReadyWorkerThreads(t, tester, "counter"))
+
+	assertAdminPatch(t, tester, "workers/counter", `{"num": 0}`, http.StatusBadRequest, "")
+	assertAdminPatch(t, tester, "workers/counter", `{}`, http.StatusBadRequest, "")
+	assertAdminPatch(t, tester, "workers/unknown", `{"num": 2}`, http.StatusNotFound, "")
+
+	assertAdminPatch(t, tester, "threads", `{"max_threads": 4}`, http.StatusOK, "threads updated successfully\n")
+	assertAdminPatch(t, tester, "threads", `{"max_threads": 1}`, http.StatusBadRequest, "")
+	assertAdminPatch(t, tester, "threads", `{"max_threads": 7}`, http.StatusBadRequest, "")
+}
+
+func assertAdminPatch(t *testing.T, tester *caddytest.Tester, path string, body string, expectedStatus int, expectedBody string) {
+	t.Helper()
+	r, err := http.NewRequest(http.MethodPatch, "http://localhost:2999/frankenphp/"+path, strings.NewReader(body))
+	require.NoError(t, err)
+	r.Header.Set("Content-Type", "application/json")
+	if expectedBody == "" {
+		_ = tester.AssertResponseCode(r, expectedStatus)
+		return
+	}
+	_, _ = tester.AssertResponse(r, expectedStatus, expectedBody)
+}
+
 func TestShowTheCorrectThreadDebugStatus(t *testing.T) {
 	tester := caddytest.NewTester(t)
 	tester.InitServer(`
@@ -87,6 +188,64 @@ func TestShowTheCorrectThreadDebugStatus(t *testing.T) {
 	assert.Len(t, debugState.ThreadDebugStates, 3)
 }
 
+func TestFilterThreadDebugStatus(t *testing.T) {
+	tester := caddytest.NewTester(t)
+	tester.InitServer(`
+		{
+			skip_install_trust
+			admin localhost:2999
+			http_port `+testPort+`
+
+			frankenphp {
+				num_threads 4
+				worker {
+					name counter
+					file ../testdata/worker-with-counter.php
+					num 2
+				}
+				worker {
+					name index
+					file ../testdata/index.php
+					num 1
+				}
+			}
+		}
+
+		localhost:`+testPort+` {
+			route {
+				root ../testdata
+				rewrite worker-with-counter.php
+				php
+			}
+		}
+		`, "caddyfile")
+
+	tester.AssertGetResponse("http://localhost:"+testPort+"/", http.StatusOK, "requests:1")
+
+	var debugState frankenphp.FrankenPHPDebugState
+	require.NoError(t, json.Unmarshal([]byte(getAdminResponseBody(t, tester, "GET", "threads?worker=counter")), &debugState))
+
+	require.Len(t, debugState.ThreadDebugStates, 2)
+	for _, s := range debugState.ThreadDebugStates {
+		assert.Equal(t, "counter", s.WorkerName)
+		assert.Equal(t, 0, s.ConsecutiveFailures)
+	}
+
+	require.Len(t, debugState.WorkerDebugStates, 1)
+	assert.Equal(t, "counter", debugState.WorkerDebugStates[0].Name)
+	assert.Equal(t, 2, debugState.WorkerDebugStates[0].IdleThreads+debugState.WorkerDebugStates[0].BusyThreads)
+	assert.Equal(t, 0, debugState.WorkerDebugStates[0].QueuedRequests)
+
+	debugState = frankenphp.FrankenPHPDebugState{}
+	require.NoError(t, json.Unmarshal([]byte(getAdminResponseBody(t, tester, "GET", "threads?state=busy&worker=index")), &debugState))
+	assert.Empty(t, debugState.ThreadDebugStates, "no request is running")
+
+	debugState = frankenphp.FrankenPHPDebugState{}
+	require.NoError(t, json.Unmarshal([]byte(getAdminResponseBody(t, tester, "GET", "threads?state=ready")), &debugState))
+	assert.Len(t, debugState.ThreadDebugStates, 4)
+	assert.Len(t, debugState.WorkerDebugStates, 2)
+}
+
 func TestThreadDebugStateMetricsAfterRequests(t *testing.T) {
 	tester := caddytest.NewTester(t)
 	tester.InitServer(`
@@ -270,6 +429,20 @@ func getDebugState(t *testing.T, tester *caddytest.Tester) frankenphp.FrankenPHP
 	return debugStates
 }
 
+// countReadyWorkerThreads counts the threads of the worker ready to handle requests, inactive threads are listed too
+func countReadyWorkerThreads(t *testing.T, tester *caddytest.Tester, worker string) int {
+	t.Helper()
+
+	n := 0
+	for _, s := range getDebugState(t, tester).ThreadDebugStates {
+		if s.WorkerName == worker && s.State == "ready" {
+			n++
+		}
+	}
+
+	return n
+}
+
 func getNumThreads(t *testing.T, tester *caddytest.Tester) int {
 	t.Helper()
 	return len(getDebugState(t, tester).ThreadDebugStates)
@@ -5,7 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"net/http"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -18,6 +21,8 @@ import (
 	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
 	"github.com/dunglas/frankenphp"
 	"github.com/dunglas/frankenphp/internal/fastabs"
+	"github.com/dustin/go-humanize"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 var (
@@ -59,9 +64,34 @@ type FrankenPHPApp struct {
 	MaxIdleTime time.Duration `json:"max_idle_time,omitempty"`
 	// EXPERIMENTAL: MaxRequests sets the maximum number of requests a PHP thread handles before restarting (0 = unlimited)
 	MaxRequests int `json:"max_requests,omitempty"`
+	// MaxMemory sets the memory usage in bytes above which a PHP thread is restarted after a request (0 = unlimited)
+	MaxMemory int64 `json:"max_memory,omitempty"`
+	// CacheSize sets the memory limit in bytes of the cache shared by all PHP threads. Default: 32MB
+	CacheSize int64 `json:"cache_size,omitempty"`
+	// SSEHistorySize sets the number of events kept per channel of frankenphp_sse_publish() for replay (-1 = disabled). Default: 100
+	SSEHistorySize int `json:"sse_history_size,omitempty"`
+	// SlowlogTimeout logs the PHP backtrace of requests running longer than this duration (0 = disabled)
+	SlowlogTimeout time.Duration `json:"slowlog_timeout,omitempty"`
+	// ScalingPolicy selects when threads are added at runtime: "cpu" (default), "queue_depth" or "latency"
+	ScalingPolicy string `json:"scaling_policy,omitempty"`
+	// ScalingQueueDepth sets the number of queued requests from which the "queue_depth" policy adds a thread. Default: 1
+	ScalingQueueDepth int `json:"scaling_queue_depth,omitempty"`
+	// ScalingLatencyTarget sets the 95th percentile of the wait time from which the "latency" policy adds a thread
+	ScalingLatencyTarget time.Duration `json:"scaling_latency_target,omitempty"`
+	// MaxQueueLength sets the maximum number of requests waiting for a thread, additional requests are rejected (0 = unlimited)
+	MaxQueueLength int `json:"max_queue_length,omitempty"`
+	// QueueFullStatus sets the status code sent when a request is rejected because the queue is full. Default: 503
+	QueueFullStatus int `json:"queue_full_status,omitempty"`
+	// QueueFullRetryAfter sets the Retry-After header sent when a request is rejected because the queue is full
+	QueueFullRetryAfter time.Duration `json:"queue_full_retry_after,omitempty"`
+	// Tracing exports OpenTelemetry spans of the PHP requests to an OTLP collector
+	Tracing *tracingConfig `json:"tracing,omitempty"`
+	// Schedules runs PHP scripts on regular threads according to cron expressions
+	Schedules []scheduleConfig `json:"schedules,omitempty"`
 
 	opts            []frankenphp.Option
 	metrics         frankenphp.Metrics
+	tracerProvider  *sdktrace.TracerProvider
 	ctx             context.Context
 	logger          *slog.Logger
 	modules         []*FrankenPHPModule
@@ -128,8 +158,36 @@ func (f *FrankenPHPApp) Start() error {
 		frankenphp.WithMaxWaitTime(f.MaxWaitTime),
 		frankenphp.WithMaxIdleTime(f.MaxIdleTime),
 		frankenphp.WithMaxRequests(f.MaxRequests),
+		frankenphp.WithMaxThreadMemory(f.MaxMemory),
+		frankenphp.WithSlowlogTimeout(f.SlowlogTimeout),
+		frankenphp.WithCacheMaxSize(f.CacheSize),
+		frankenphp.WithSSEHistorySize(f.SSEHistorySize),
+		frankenphp.WithMaxQueueLength(f.MaxQueueLength),
 	)
 
+	scalingPolicy, err := f.newScalingPolicy()
+	if err != nil {
+		return err
+	}
+	f.opts = append(f.opts, frankenphp.WithScalingPolicy(scalingPolicy))
+
+	if f.QueueFullStatus != 0 || f.QueueFullRetryAfter != 0 {
+		status := f.QueueFullStatus
+		if status == 0 {
+			status = http.StatusServiceUnavailable
+		}
+
+		f.opts = append(f.opts, frankenphp.WithQueueFullResponse(status, f.QueueFullRetryAfter))
+	}
+
+	if f.Tracing != nil {
+		if f.tracerProvider, err = f.Tracing.newTracerProvider(f.ctx); err != nil {
+			return err
+		}
+
+		f.opts = append(f.opts, frankenphp.WithTracerProvider(f.tracerProvider))
+	}
+
 	// register global workers
 	for _, w := range f.Workers {
 		w.FileName = repl.ReplaceKnown(w.FileName, "")
@@ -141,6 +199,14 @@ func (f *FrankenPHPApp) Start() error {
 		f.opts = append(f.opts, frankenphp.WithWorkers(w.Name, w.FileName, w.Num, opts...))
 	}
 
+	for _, s := range f.Schedules {
+		opt, err := s.toOption(repl)
+		if err != nil {
+			return err
+		}
+		f.opts = append(f.opts, opt)
+	}
+
 	if err := f.registerModules(repl); err != nil {
 		return err
 	}
@@ -169,6 +235,13 @@ func (f *FrankenPHPApp) Stop() error {
 		frankenphp.Shutdown()
 	}
 
+	// flush the spans that have not been exported yet
+	if f.tracerProvider != nil {
+		if err := f.tracerProvider.Shutdown(context.Background()); err != nil && f.logger.Enabled(f.ctx, slog.LevelWarn) {
+			f.logger.LogAttrs(f.ctx, slog.LevelWarn, "unable to export the remaining spans", slog.Any("error", err))
+		}
+	}
+
 	// reset global options
 	optionsMU.Lock()
 	options = nil
@@ -177,6 +250,37 @@ func (f *FrankenPHPApp) Stop() error {
 	return nil
 }
 
+// parseMemorySize parses a human-readable size such as "128MB" or "1GiB"
+func parseMemorySize(size string) (int64, error) {
+	v, err := humanize.ParseBytes(size)
+	if err != nil {
+		return 0, err
+	}
+	if v > math.MaxInt64 {
+		return 0, fmt.Errorf("memory size %q is too large", size)
+	}
+
+	return int64(v), nil
+}
+
+// newScalingPolicy creates the scaling policy selected with the "scaling_policy" directive
+func (f *FrankenPHPApp) newScalingPolicy() (frankenphp.ScalingPolicy, error) {
+	switch f.ScalingPolicy {
+	case "", "cpu":
+		return frankenphp.NewCPUScalingPolicy(), nil
+	case "queue_depth":
+		return frankenphp.NewQueueDepthScalingPolicy(f.ScalingQueueDepth), nil
+	case "latency":
+		if f.ScalingLatencyTarget <= 0 {
+			return nil, errors.New(`the "latency" scaling policy requires a target duration`)
+		}
+
+		return frankenphp.NewLatencyScalingPolicy(f.ScalingLatencyTarget), nil
+	default:
+		return nil, fmt.Errorf(`unknown scaling policy %q (allowed policies are: cpu, queue_depth, latency)`, f.ScalingPolicy)
+	}
+}
+
 // register workers and servers for "php" and "php_server" modules
 func (f *FrankenPHPApp) registerModules(repl *caddy.Replacer) error {
 	modulesByIndex := make(map[int]*FrankenPHPModule, len(f.modules))
@@ -214,6 +318,8 @@ func (f *FrankenPHPApp) registerModule(repl *caddy.Replacer, module *FrankenPHPM
 		frankenphp.WithServerSplitPath(module.SplitPath),
 		frankenphp.WithServerEnv(module.resolvedEnv),
 		frankenphp.WithServerLogger(module.logger),
+		frankenphp.WithServerMinThreads(module.MinThreads),
+		frankenphp.WithServerMaxThreads(module.MaxThreads),
 	)
 	if err != nil {
 		return err
@@ -333,6 +439,143 @@ func (f *FrankenPHPApp) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
 				}
 
 				f.MaxRequests = int(v)
+			case "max_memory":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+
+				v, err := parseMemorySize(d.Val())
+				if err != nil {
+					return d.WrapErr(err)
+				}
+
+				f.MaxMemory = v
+			case "cache_size":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+
+				v, err := parseMemorySize(d.Val())
+				if err != nil {
+					return d.WrapErr(err)
+				}
+
+				f.CacheSize = v
+			case "sse_history_size":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+
+				v, err := strconv.Atoi(d.Val())
+				if err != nil || v < -1 {
+					return d.Err("sse_history_size must be a number of events, or -1 to disable the replay")
+				}
+
+				f.SSEHistorySize = v
+			case "slowlog_timeout":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+
+				v, err := time.ParseDuration(d.Val())
+				if err != nil {
+					return d.Err("slowlog_timeout must be a valid duration (example: 5s)")
+				}
+
+				f.SlowlogTimeout = v
+			case "scaling_policy":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+
+				f.ScalingPolicy = d.Val()
+				switch f.ScalingPolicy {
+				case "cpu":
+				case "queue_depth":
+					if d.NextArg() {
+						v, err := strconv.ParseUint(d.Val(), 10, 32)
+						if err != nil {
+							return d.WrapErr(err)
+						}
+
+						f.ScalingQueueDepth = int(v)
+					}
+				case "latency":
+					if !d.NextArg() {
+						return d.Err(`the "latency" scaling policy requires a target duration (example: scaling_policy latency 200ms)`)
+					}
+
+					v, err := time.ParseDuration(d.Val())
+					if err != nil {
+						return d.Err("the latency target must be a valid duration (example: 200ms)")
+					}
+
+					f.ScalingLatencyTarget = v
+				default:
+					return d.Errf("unknown scaling policy %q (allowed policies are: cpu, queue_depth, latency)", f.ScalingPolicy)
+				}
+
+				if d.NextArg() {
+					return d.ArgErr()
+				}
+			case "max_queue":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+
+				v, err := strconv.ParseUint(d.Val(), 10, 32)
+				if err != nil {
+					return d.WrapErr(err)
+				}
+
+				f.MaxQueueLength = int(v)
+
+				for d.NextBlock(1) {
+					switch d.Val() {
+					case "status":
+						if !d.NextArg() {
+							return d.ArgErr()
+						}
+
+						v, err := strconv.Atoi(d.Val())
+						if err != nil || v < 400 || v > 599 {
+							return d.Err("max_queue status must be an error status code (example: 503)")
+						}
+
+						f.QueueFullStatus = v
+					case "retry_after":
+						if !d.NextArg() {
+							return d.ArgErr()
+						}
+
+						v, err := time.ParseDuration(d.Val())
+						if err != nil {
+							return d.Err("retry_after must be a valid duration (example: 10s)")
+						}
+
+						f.QueueFullRetryAfter = v
+					default:
+						return wrongSubDirectiveError("max_queue", "status, retry_after", d.Val())
+					}
+				}
+			case "tracing":
+				c, err := unmarshalTracing(d)
+				if err != nil {
+					return err
+				}
+
+				f.Tracing = c
+			case "schedule":
+				c, err := unmarshalSchedule(d)
+				if err != nil {
+					return err
+				}
+
+				if slices.ContainsFunc(f.Schedules, func(s scheduleConfig) bool { return s.Name == c.Name }) {
+					return d.Errf("schedules must have unique names: %q", c.Name)
+				}
+
+				f.Schedules = append(f.Schedules, c)
 			case "php_ini":
 				parseIniLine := func(d *caddyfile.Dispenser) error {
 					key := d.Val()
@@ -383,14 +626,21 @@ func (f *FrankenPHPApp) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
 				}
 				// check for duplicate workers
 				for _, existingWorker := range f.Workers {
-					if existingWorker.FileName == wc.FileName {
+					if !existingWorker.Background && existingWorker.FileName == wc.FileName {
 						return d.Errf("global workers must not have duplicate filenames: %q", wc.FileName)
 					}
 				}
 
+				f.Workers = append(f.Workers, wc)
+			case "background_worker":
+				wc, err := unmarshalBackgroundWorker(d)
+				if err != nil {
+					return err
+				}
+
 				f.Workers = append(f.Workers, wc)
 			default:
-				return wrongSubDirectiveError("frankenphp", "num_threads, max_threads, php_ini, worker, max_wait_time, max_idle_time, max_requests", d.Val())
+				return wrongSubDirectiveError("frankenphp", "num_threads, max_threads, php_ini, worker, background_worker, max_wait_time, max_idle_time, max_requests, max_memory, cache_size, sse_history_size, slowlog_timeout, max_queue, scaling_policy, tracing, schedule", d.Val())
 			}
 		}
 	}
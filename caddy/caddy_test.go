@@ -829,19 +829,19 @@ func TestWorkerMetrics(t *testing.T) {
 
 	# HELP frankenphp_busy_workers Number of busy PHP workers for this worker
 	# TYPE frankenphp_busy_workers gauge
-	frankenphp_busy_workers{worker="` + workerName + `"} 0
+	frankenphp_busy_workers{server="",worker="` + workerName + `"} 0
 
 	# HELP frankenphp_total_workers Total number of PHP workers for this worker
 	# TYPE frankenphp_total_workers gauge
-	frankenphp_total_workers{worker="` + workerName + `"} 2
+	frankenphp_total_workers{server="",worker="` + workerName + `"} 2
 
 	# HELP frankenphp_worker_request_count
 	# TYPE frankenphp_worker_request_count counter
-	frankenphp_worker_request_count{worker="` + workerName + `"} 10
+	frankenphp_worker_request_count{server="",worker="` + workerName + `"} 10
 
 	# HELP frankenphp_ready_workers Running workers that have successfully called frankenphp_handle_request at least once
 	# TYPE frankenphp_ready_workers gauge
-	frankenphp_ready_workers{worker="` + workerName + `"} 2
+	frankenphp_ready_workers{server="",worker="` + workerName + `"} 2
 	`
 
 	ctx := caddy.ActiveContext()
@@ -904,7 +904,7 @@ func TestPhpServerWorkerMatchPoolCount(t *testing.T) {
 
 	var pools []string
 	for _, line := range strings.Split(metrics.String(), "\n") {
-		if !strings.HasPrefix(line, "frankenphp_total_workers{worker=") {
+		if !strings.HasPrefix(line, "frankenphp_total_workers{") {
 			continue
 		}
 		if !strings.Contains(line, "dedup-match-worker.php") && !strings.Contains(line, "dedup-plain-worker.php") {
@@ -986,19 +986,19 @@ func TestNamedWorkerMetrics(t *testing.T) {
 
 	# HELP frankenphp_busy_workers Number of busy PHP workers for this worker
         # TYPE frankenphp_busy_workers gauge
-        frankenphp_busy_workers{worker="my_app"} 0
+        frankenphp_busy_workers{server="",worker="my_app"} 0
 
 	# HELP frankenphp_total_workers Total number of PHP workers for this worker
 	# TYPE frankenphp_total_workers gauge
-	frankenphp_total_workers{worker="my_app"} 2
+	frankenphp_total_workers{server="",worker="my_app"} 2
 
 	# HELP frankenphp_worker_request_count
 	# TYPE frankenphp_worker_request_count counter
-	frankenphp_worker_request_count{worker="my_app"} 10
+	frankenphp_worker_request_count{server="",worker="my_app"} 10
 
 	# HELP frankenphp_ready_workers Running workers that have successfully called frankenphp_handle_request at least once
 	# TYPE frankenphp_ready_workers gauge
-	frankenphp_ready_workers{worker="my_app"} 2
+	frankenphp_ready_workers{server="",worker="my_app"} 2
 	`
 
 	ctx := caddy.ActiveContext()
@@ -1082,19 +1082,19 @@ func TestAutoWorkerConfig(t *testing.T) {
 
 	# HELP frankenphp_busy_workers Number of busy PHP workers for this worker
 	# TYPE frankenphp_busy_workers gauge
-	frankenphp_busy_workers{worker="` + workerName + `"} 0
+	frankenphp_busy_workers{server="",worker="` + workerName + `"} 0
 
 	# HELP frankenphp_total_workers Total number of PHP workers for this worker
 	# TYPE frankenphp_total_workers gauge
-	frankenphp_total_workers{worker="` + workerName + `"} ` + workers + `
+	frankenphp_total_workers{server="",worker="` + workerName + `"} ` + workers + `
 
 	# HELP frankenphp_worker_request_count
 	# TYPE frankenphp_worker_request_count counter
-	frankenphp_worker_request_count{worker="` + workerName + `"} 10
+	frankenphp_worker_request_count{server="",worker="` + workerName + `"} 10
 
 	# HELP frankenphp_ready_workers Running workers that have successfully called frankenphp_handle_request at least once
 	# TYPE frankenphp_ready_workers gauge
-	frankenphp_ready_workers{worker="` + workerName + `"} ` + workers + `
+	frankenphp_ready_workers{server="",worker="` + workerName + `"} ` + workers + `
 	`
 
 	ctx := caddy.ActiveContext()
@@ -1289,6 +1289,53 @@ func TestMaxWaitTime(t *testing.T) {
 	require.True(t, success.Load(), "At least one request should have failed with a 503 Service Unavailable status")
 }
 
+func TestMaxQueue(t *testing.T) {
+	tester := caddytest.NewTester(t)
+	initServer(t, tester, `
+		{
+			skip_install_trust
+			admin localhost:2999
+			http_port `+testPort+`
+
+			frankenphp {
+				num_threads 1
+				max_queue 1 {
+					status 429
+					retry_after 3s
+				}
+			}
+		}
+
+		localhost:`+testPort+` {
+			route {
+				root ../testdata
+				php
+			}
+		}
+		`, "caddyfile")
+
+	// send 10 requests simultaneously, since we only have 1 thread and a queue of 1,
+	// at least one request should be shed with a 429 Too Many Requests status
+	wg := sync.WaitGroup{}
+	success := atomic.Bool{}
+	wg.Add(10)
+	for range 10 {
+		go func() {
+			resp, err := http.Get("http://localhost:" + testPort + "/sleep.php?sleep=50")
+			if err == nil {
+				if resp.StatusCode == http.StatusTooManyRequests && resp.Header.Get("Retry-After") == "3" {
+					success.Store(true)
+				}
+				_ = resp.Body.Close()
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	require.True(t, success.Load(), "At least one request should have been shed with a 429 Too Many Requests status")
+}
+
 func TestMaxWaitTimeWorker(t *testing.T) {
 	tester := caddytest.NewTester(t)
 	initServer(t, tester, `
@@ -1348,7 +1395,7 @@ func TestMaxWaitTimeWorker(t *testing.T) {
 
 	expectedMetrics := `
 	# TYPE frankenphp_worker_queue_depth gauge
-	frankenphp_worker_queue_depth{worker="service"} 0
+	frankenphp_worker_queue_depth{server="",worker="service"} 0
 	`
 
 	ctx := caddy.ActiveContext()
@@ -1449,21 +1496,21 @@ func TestMultiWorkersMetrics(t *testing.T) {
 
 	# HELP frankenphp_busy_workers Number of busy PHP workers for this worker
 	# TYPE frankenphp_busy_workers gauge
-	frankenphp_busy_workers{worker="service1"} 0
+	frankenphp_busy_workers{server="",worker="service1"} 0
 
 	# HELP frankenphp_total_workers Total number of PHP workers for this worker
 	# TYPE frankenphp_total_workers gauge
-	frankenphp_total_workers{worker="service1"} 2
-	frankenphp_total_workers{worker="service2"} 3
+	frankenphp_total_workers{server="",worker="service1"} 2
+	frankenphp_total_workers{server="",worker="service2"} 3
 
 	# HELP frankenphp_worker_request_count
 	# TYPE frankenphp_worker_request_count counter
-	frankenphp_worker_request_count{worker="service1"} 10
+	frankenphp_worker_request_count{server="",worker="service1"} 10
 
 	# HELP frankenphp_ready_workers Running workers that have successfully called frankenphp_handle_request at least once
 	# TYPE frankenphp_ready_workers gauge
-	frankenphp_ready_workers{worker="service1"} 2
-	frankenphp_ready_workers{worker="service2"} 3
+	frankenphp_ready_workers{server="",worker="service1"} 2
+	frankenphp_ready_workers{server="",worker="service2"} 3
 	`
 
 	ctx := caddy.ActiveContext()
@@ -1616,10 +1663,10 @@ func TestWorkerRestart(t *testing.T) {
 	expectedMetrics := `
 	# HELP frankenphp_ready_workers Running workers that have successfully called frankenphp_handle_request at least once
 	# TYPE frankenphp_ready_workers gauge
-	frankenphp_ready_workers{worker="service"} 1
+	frankenphp_ready_workers{server="",worker="service"} 1
 	# HELP frankenphp_total_workers Total number of PHP workers for this worker
 	# TYPE frankenphp_total_workers gauge
-	frankenphp_total_workers{worker="service"} 1
+	frankenphp_total_workers{server="",worker="service"} 1
 	`
 
 	require.NoError(t,
@@ -1644,13 +1691,13 @@ func TestWorkerRestart(t *testing.T) {
 	expectedMetrics = `
 	# HELP frankenphp_ready_workers Running workers that have successfully called frankenphp_handle_request at least once
 	# TYPE frankenphp_ready_workers gauge
-	frankenphp_ready_workers{worker="service"} 1
+	frankenphp_ready_workers{server="",worker="service"} 1
 	# HELP frankenphp_total_workers Total number of PHP workers for this worker
 	# TYPE frankenphp_total_workers gauge
-	frankenphp_total_workers{worker="service"} 1
+	frankenphp_total_workers{server="",worker="service"} 1
 	# HELP frankenphp_worker_restarts Number of PHP worker restarts for this worker
 	# TYPE frankenphp_worker_restarts counter
-	frankenphp_worker_restarts{worker="service"} 3
+	frankenphp_worker_restarts{server="",worker="service"} 3
 	`
 
 	require.NoError(t,
@@ -284,3 +284,456 @@ func TestCreateUniqueWorkerNamesQualifiedByServer(t *testing.T) {
 	// workers without a server keep the numeric postfix behavior
 	require.Equal(t, "queue_2", app.createUniqueWorkerName(wc, ""))
 }
+
+func TestModuleWorkerWithPriorities(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			worker {
+				file ../testdata/worker-with-env.php
+				priority 10 {
+					match /api/*
+					weight 5
+					max_wait_time 2s
+				}
+				priority -1 {
+					match /reports/*
+				}
+			}
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	require.NoError(t, module.UnmarshalCaddyfile(d))
+	require.Len(t, module.Workers, 1)
+	require.Equal(t, []workerPriorityConfig{
+		{Priority: 10, MatchPath: []string{"/api/*"}, Weight: 5, MaxWaitTime: 2 * time.Second},
+		{Priority: -1, MatchPath: []string{"/reports/*"}},
+	}, module.Workers[0].Priorities)
+
+	_, err := module.Workers[0].toWorkerOptions()
+	require.NoError(t, err)
+}
+
+func TestModuleWorkerPriorityWithoutMatchFails(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			worker {
+				file ../testdata/worker-with-env.php
+				priority 10 {
+					weight 5
+				}
+			}
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	err := module.UnmarshalCaddyfile(d)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `"match" subdirective of "priority" must be specified`)
+}
+
+func TestAppScalingPolicy(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	frankenphp {
+		scaling_policy latency 200ms
+	}`)
+	app := &FrankenPHPApp{}
+
+	require.NoError(t, app.UnmarshalCaddyfile(d))
+	require.Equal(t, "latency", app.ScalingPolicy)
+	require.Equal(t, 200*time.Millisecond, app.ScalingLatencyTarget)
+
+	_, err := app.newScalingPolicy()
+	require.NoError(t, err)
+}
+
+func TestAppSlowlogTimeout(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	frankenphp {
+		slowlog_timeout 5s
+	}`)
+	app := &FrankenPHPApp{}
+
+	require.NoError(t, app.UnmarshalCaddyfile(d))
+	require.Equal(t, 5*time.Second, app.SlowlogTimeout)
+}
+
+func TestAppTracing(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	frankenphp {
+		tracing {
+			endpoint http://collector:4318
+			protocol http
+			insecure
+			sample_ratio 0.25
+			service_name my-app
+		}
+	}`)
+	app := &FrankenPHPApp{}
+
+	require.NoError(t, app.UnmarshalCaddyfile(d))
+	require.NotNil(t, app.Tracing)
+	require.Equal(t, "http://collector:4318", app.Tracing.Endpoint)
+	require.Equal(t, "http", app.Tracing.Protocol)
+	require.True(t, app.Tracing.Insecure)
+	require.Equal(t, 0.25, *app.Tracing.SampleRatio)
+	require.Equal(t, "my-app", app.Tracing.ServiceName)
+}
+
+func TestAppTracingInvalidSampleRatioFails(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	frankenphp {
+		tracing {
+			sample_ratio 2
+		}
+	}`)
+	app := &FrankenPHPApp{}
+
+	err := app.UnmarshalCaddyfile(d)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "sample_ratio must be a number between 0 and 1")
+}
+
+func TestAppSchedule(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	frankenphp {
+		schedule cleanup {
+			cron "*/5 * * * *"
+			file ../testdata/scheduled-task.php
+			args app:cleanup --force
+			env APP_ENV prod
+			timeout 5m
+			overlap queue
+		}
+		schedule report {
+			cron @daily
+			file ../testdata/scheduled-task.php
+		}
+	}`)
+	app := &FrankenPHPApp{}
+
+	require.NoError(t, app.UnmarshalCaddyfile(d))
+	require.Len(t, app.Schedules, 2)
+	require.Equal(t, scheduleConfig{
+		Name:     "cleanup",
+		Cron:     "*/5 * * * *",
+		FileName: "../testdata/scheduled-task.php",
+		Args:     []string{"app:cleanup", "--force"},
+		Env:      map[string]string{"APP_ENV": "prod"},
+		Timeout:  5 * time.Minute,
+		Overlap:  "queue",
+	}, app.Schedules[0])
+	require.Equal(t, "@daily", app.Schedules[1].Cron)
+}
+
+func TestAppScheduleInvalidOverlapFails(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	frankenphp {
+		schedule cleanup {
+			cron @hourly
+			file ../testdata/scheduled-task.php
+			overlap replace
+		}
+	}`)
+	app := &FrankenPHPApp{}
+
+	err := app.UnmarshalCaddyfile(d)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown overlap policy")
+}
+
+func TestAppScheduleDuplicateNameFails(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	frankenphp {
+		schedule cleanup {
+			cron @hourly
+			file ../testdata/scheduled-task.php
+		}
+		schedule cleanup {
+			cron @daily
+			file ../testdata/scheduled-task.php
+		}
+	}`)
+	app := &FrankenPHPApp{}
+
+	require.Error(t, app.UnmarshalCaddyfile(d))
+}
+
+func TestAppUnknownScalingPolicyFails(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	frankenphp {
+		scaling_policy random
+	}`)
+	app := &FrankenPHPApp{}
+
+	err := app.UnmarshalCaddyfile(d)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unknown scaling policy "random"`)
+}
+
+func TestAppCacheSize(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	frankenphp {
+		cache_size 64MB
+	}`)
+	app := &FrankenPHPApp{}
+
+	require.NoError(t, app.UnmarshalCaddyfile(d))
+	require.Equal(t, int64(64_000_000), app.CacheSize)
+}
+
+func TestAppSSEHistorySize(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	frankenphp {
+		sse_history_size 500
+	}`)
+	app := &FrankenPHPApp{}
+
+	require.NoError(t, app.UnmarshalCaddyfile(d))
+	require.Equal(t, 500, app.SSEHistorySize)
+}
+
+func TestModuleWorkerMaxMemory(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			worker {
+				file ../testdata/worker-with-env.php
+				max_memory 128MB
+			}
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	require.NoError(t, module.UnmarshalCaddyfile(d))
+	require.Len(t, module.Workers, 1)
+	require.Equal(t, int64(128_000_000), module.Workers[0].MaxMemory)
+}
+
+func TestModuleWorkerRestartStrategy(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			worker {
+				file ../testdata/worker-with-env.php
+				restart_strategy rolling
+			}
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	require.NoError(t, module.UnmarshalCaddyfile(d))
+	require.Len(t, module.Workers, 1)
+	require.Equal(t, "rolling", module.Workers[0].RestartStrategy)
+
+	d = caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			worker {
+				file ../testdata/worker-with-env.php
+				restart_strategy blue-green
+			}
+		}
+	}`)
+
+	require.Error(t, (&FrankenPHPModule{}).UnmarshalCaddyfile(d))
+}
+
+func TestModuleRequestTimeout(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php {
+			request_timeout 30s
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	require.NoError(t, module.UnmarshalCaddyfile(d))
+	require.Equal(t, caddy.Duration(30*time.Second), module.RequestTimeout)
+}
+
+func TestModuleMaxThreads(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			min_threads 2
+			max_threads 4
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	require.NoError(t, module.UnmarshalCaddyfile(d))
+	require.Equal(t, 2, module.MinThreads)
+	require.Equal(t, 4, module.MaxThreads)
+}
+
+func TestModuleInvalidMaxThreadsFails(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			max_threads -1
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	require.Error(t, module.UnmarshalCaddyfile(d))
+}
+
+func TestModuleWorkerSubscribe(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			worker {
+				file ../testdata/worker-with-env.php
+				subscribe products orders
+				subscribe users
+			}
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	require.NoError(t, module.UnmarshalCaddyfile(d))
+	require.Len(t, module.Workers, 1)
+	require.Equal(t, []string{"products", "orders", "users"}, module.Workers[0].Subscribe)
+}
+
+func TestModuleWorkerWebSocket(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			worker {
+				file ../testdata/websocket.php
+				match /ws
+				websocket https://example.com https://app.example.com
+			}
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	require.NoError(t, module.UnmarshalCaddyfile(d))
+	require.Len(t, module.Workers, 1)
+	require.True(t, module.Workers[0].WebSocket)
+	require.Equal(t, []string{"https://example.com", "https://app.example.com"}, module.Workers[0].WebSocketOrigins)
+}
+
+func TestModuleBackgroundWorker(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			background_worker {
+				file ../testdata/background-worker.php
+				env BACKGROUND_WORKER_FILE /tmp/background-worker.log
+			}
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	require.NoError(t, module.UnmarshalCaddyfile(d))
+	require.Len(t, module.Workers, 1)
+	require.True(t, module.Workers[0].Background)
+	require.Equal(t, "/tmp/background-worker.log", module.Workers[0].Env["BACKGROUND_WORKER_FILE"])
+}
+
+func TestModuleBackgroundWorkerWithMatch(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			background_worker {
+				file ../testdata/background-worker.php
+				match /jobs/*
+			}
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	require.Error(t, module.UnmarshalCaddyfile(d))
+}
+
+func TestModuleBackgroundWorkerWithWebSocket(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			background_worker {
+				file ../testdata/background-worker.php
+				websocket
+			}
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	require.Error(t, module.UnmarshalCaddyfile(d))
+}
+
+func TestModuleSendfile(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			sendfile x-accel-redirect {
+				root private-files/
+				mapping ../private-files=/private-files
+			}
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	require.NoError(t, module.UnmarshalCaddyfile(d))
+	require.NotNil(t, module.Sendfile)
+	require.Equal(t, "x-accel-redirect", module.Sendfile.Type)
+	require.Equal(t, "private-files/", module.Sendfile.Root)
+	require.Equal(t, map[string]string{"/private-files": "../private-files"}, module.Sendfile.Mappings)
+}
+
+func TestModuleSendfileInvalidTypeFails(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			sendfile x-lighttpd-send-file
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	require.Error(t, module.UnmarshalCaddyfile(d))
+}
+
+func TestModuleSendfileInvalidMappingFails(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			sendfile x-accel-redirect {
+				mapping ../private-files
+			}
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	require.Error(t, module.UnmarshalCaddyfile(d))
+}
+
+func TestModuleOutputBufferingMode(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			output_buffering_mode detached
+			output_buffer_size 4MB
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	require.NoError(t, module.UnmarshalCaddyfile(d))
+	require.Equal(t, "detached", module.OutputBufferingMode)
+	require.Equal(t, int64(4_000_000), module.OutputBufferSize)
+}
+
+func TestModuleInvalidOutputBufferingModeFails(t *testing.T) {
+	d := caddyfile.NewTestDispenser(`
+	{
+		php_server {
+			output_buffering_mode async
+		}
+	}`)
+	module := &FrankenPHPModule{}
+
+	require.Error(t, module.UnmarshalCaddyfile(d))
+}
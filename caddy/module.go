@@ -51,8 +51,20 @@ type FrankenPHPModule struct {
 	ServerIndex int `json:"server_index,omitempty"`
 	// RequestBodyTimeout is an idle timeout on request body reads: a stalled (slow POST) client is cut off while a steady upload of any size succeeds. Defaults to 60s when omitted; set to 0 to disable.
 	RequestBodyTimeout *caddy.Duration `json:"request_body_timeout,omitempty"`
+	// RequestTimeout is the maximum wall clock time PHP may spend executing a request, a 504 is returned when it is exceeded. Default: disabled.
+	RequestTimeout caddy.Duration `json:"request_timeout,omitempty"`
 	// Name is the name of the php_server this module belongs to for logging purposes
 	Name string `json:"name,omitempty"`
+	// MinThreads is the number of regular threads dedicated to this php_server. Default: 0 (only shared threads)
+	MinThreads int `json:"min_threads,omitempty"`
+	// MaxThreads limits the number of regular threads handling requests of this php_server at the same time. Default: 0 (unlimited)
+	MaxThreads int `json:"max_threads,omitempty"`
+	// Sendfile sends the file designated by the X-Sendfile or X-Accel-Redirect header set by PHP in place of the response body.
+	Sendfile *sendfileConfig `json:"sendfile,omitempty"`
+	// OutputBufferingMode sets how the output of PHP is sent: "sync" (default) writes it to the client directly, "detached" buffers it and releases the PHP thread without waiting for slow clients.
+	OutputBufferingMode string `json:"output_buffering_mode,omitempty"`
+	// OutputBufferSize sets the maximum size of the buffer of each response in detached mode, PHP waits for the client when it is full. Default: 1MB.
+	OutputBufferSize int64 `json:"output_buffer_size,omitempty"`
 
 	resolvedDocumentRoot string
 	resolvedEnv          map[string]string
@@ -130,6 +142,26 @@ func (f *FrankenPHPModule) Provision(ctx caddy.Context) error {
 		f.requestOptions = append(f.requestOptions, frankenphp.WithRequestBodyTimeout(time.Duration(*f.RequestBodyTimeout)))
 	}
 
+	if f.RequestTimeout > 0 {
+		f.requestOptions = append(f.requestOptions, frankenphp.WithRequestTimeout(time.Duration(f.RequestTimeout)))
+	}
+
+	switch f.OutputBufferingMode {
+	case "", "sync":
+	case "detached":
+		f.requestOptions = append(f.requestOptions, frankenphp.WithRequestDetachedOutput(int(f.OutputBufferSize)))
+	default:
+		return fmt.Errorf(`invalid output_buffering_mode %q, expected "sync" or "detached"`, f.OutputBufferingMode)
+	}
+
+	if f.Sendfile != nil {
+		opt, err := f.Sendfile.toRequestOption()
+		if err != nil {
+			return fmt.Errorf("invalid sendfile: %w", err)
+		}
+		f.requestOptions = append(f.requestOptions, opt)
+	}
+
 	if f.ResolveRootSymlink == nil {
 		f.ResolveRootSymlink = new(true)
 	}
@@ -280,6 +312,25 @@ func (f *FrankenPHPModule) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
 				}
 				f.Name = d.Val()
 
+			case "min_threads", "max_threads":
+				directive := d.Val()
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				v, err := strconv.ParseUint(d.Val(), 10, 32)
+				if err != nil {
+					return d.WrapErr(err)
+				}
+				if d.NextArg() {
+					return d.ArgErr()
+				}
+
+				if directive == "min_threads" {
+					f.MinThreads = int(v)
+				} else {
+					f.MaxThreads = int(v)
+				}
+
 			case "worker":
 				wc, err := unmarshalWorker(d)
 				if err != nil {
@@ -288,6 +339,47 @@ func (f *FrankenPHPModule) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
 
 				f.Workers = append(f.Workers, wc)
 
+			case "background_worker":
+				wc, err := unmarshalBackgroundWorker(d)
+				if err != nil {
+					return err
+				}
+
+				f.Workers = append(f.Workers, wc)
+
+			case "output_buffering_mode":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				if v := d.Val(); v != "sync" && v != "detached" {
+					return d.Errf(`invalid output_buffering_mode %q, expected "sync" or "detached"`, v)
+				}
+				f.OutputBufferingMode = d.Val()
+				if d.NextArg() {
+					return d.ArgErr()
+				}
+
+			case "output_buffer_size":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				v, err := parseMemorySize(d.Val())
+				if err != nil {
+					return d.WrapErr(err)
+				}
+				if d.NextArg() {
+					return d.ArgErr()
+				}
+				f.OutputBufferSize = v
+
+			case "sendfile":
+				c, err := unmarshalSendfile(d)
+				if err != nil {
+					return err
+				}
+
+				f.Sendfile = c
+
 			case "hot_reload":
 				if err := f.unmarshalHotReload(d); err != nil {
 					return err
@@ -306,8 +398,21 @@ func (f *FrankenPHPModule) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
 				}
 				f.RequestBodyTimeout = new(caddy.Duration(v))
 
+			case "request_timeout":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				v, err := caddy.ParseDuration(d.Val())
+				if err != nil {
+					return err
+				}
+				if d.NextArg() {
+					return d.ArgErr()
+				}
+				f.RequestTimeout = caddy.Duration(v)
+
 			default:
-				return wrongSubDirectiveError("php or php_server", "hot_reload, name, root, split, env, resolve_root_symlink, request_body_timeout, worker", d.Val())
+				return wrongSubDirectiveError("php or php_server", "hot_reload, name, root, split, env, resolve_root_symlink, request_body_timeout, request_timeout, min_threads, max_threads, worker, background_worker, sendfile, output_buffering_mode, output_buffer_size", d.Val())
 			}
 		}
 	}
@@ -315,6 +420,10 @@ func (f *FrankenPHPModule) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
 	// Check if a worker with this filename already exists in this module
 	fileNames := make(map[string]struct{}, len(f.Workers))
 	for _, w := range f.Workers {
+		if w.Background {
+			continue
+		}
+
 		if _, ok := fileNames[w.FileName]; ok {
 			return fmt.Errorf(`workers in a single "php" or "php_server" block must not have duplicate filenames: %q`, w.FileName)
 		}
@@ -0,0 +1,141 @@
+package caddy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/dunglas/frankenphp"
+)
+
+// scheduleConfig represents a "schedule" block of the global "frankenphp" directive
+//
+//	{
+//		frankenphp {
+//			schedule cleanup {
+//				cron "*/5 * * * *"
+//				file /app/bin/console
+//				args app:cleanup --force
+//				env APP_ENV prod
+//				timeout 5m
+//				overlap skip
+//			}
+//		}
+//	}
+type scheduleConfig struct {
+	// Name identifies the task in logs and metrics
+	Name string `json:"name,omitempty"`
+	// Cron is the standard 5-field cron expression or one of the @yearly, @monthly, @weekly, @daily and @hourly macros
+	Cron string `json:"cron,omitempty"`
+	// FileName sets the path to the PHP script to run
+	FileName string `json:"file_name,omitempty"`
+	// Args sets the arguments passed to the script in $argv
+	Args []string `json:"args,omitempty"`
+	// Env sets extra environment variables for the script
+	Env map[string]string `json:"env,omitempty"`
+	// Timeout sets the maximum duration of a run (0 = unlimited)
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// Overlap sets what happens when the task is due while the previous run is still running: "skip" (default), "allow" or "queue"
+	Overlap string `json:"overlap,omitempty"`
+}
+
+func (c scheduleConfig) toOption(repl *caddy.Replacer) (frankenphp.Option, error) {
+	overlap, err := parseOverlapPolicy(c.Overlap)
+	if err != nil {
+		return nil, err
+	}
+
+	return frankenphp.WithScheduledTask(c.Name, c.Cron, repl.ReplaceKnown(c.FileName, ""),
+		frankenphp.WithScheduledTaskArgs(c.Args...),
+		frankenphp.WithScheduledTaskEnv(c.Env),
+		frankenphp.WithScheduledTaskTimeout(c.Timeout),
+		frankenphp.WithScheduledTaskOverlapPolicy(overlap),
+	), nil
+}
+
+func unmarshalSchedule(d *caddyfile.Dispenser) (scheduleConfig, error) {
+	c := scheduleConfig{}
+
+	if !d.NextArg() {
+		return c, d.Err(`the "schedule" directive requires a name (example: schedule cleanup { ... })`)
+	}
+	c.Name = d.Val()
+
+	if d.NextArg() {
+		return c, d.ArgErr()
+	}
+
+	for d.NextBlock(1) {
+		switch d.Val() {
+		case "cron":
+			// the expression can be quoted or not
+			args := d.RemainingArgs()
+			if len(args) == 0 {
+				return c, d.ArgErr()
+			}
+
+			c.Cron = strings.Join(args, " ")
+		case "file":
+			if !d.NextArg() {
+				return c, d.ArgErr()
+			}
+
+			c.FileName = d.Val()
+		case "args":
+			c.Args = append(c.Args, d.RemainingArgs()...)
+		case "env":
+			args := d.RemainingArgs()
+			if len(args) != 2 {
+				return c, d.ArgErr()
+			}
+			if c.Env == nil {
+				c.Env = make(map[string]string)
+			}
+			c.Env[args[0]] = args[1]
+		case "timeout":
+			if !d.NextArg() {
+				return c, d.ArgErr()
+			}
+
+			v, err := time.ParseDuration(d.Val())
+			if err != nil {
+				return c, d.Err("timeout must be a valid duration (example: 5m)")
+			}
+
+			c.Timeout = v
+		case "overlap":
+			if !d.NextArg() {
+				return c, d.ArgErr()
+			}
+
+			if _, err := parseOverlapPolicy(d.Val()); err != nil {
+				return c, d.WrapErr(err)
+			}
+
+			c.Overlap = d.Val()
+		default:
+			return c, wrongSubDirectiveError("schedule", "cron, file, args, env, timeout, overlap", d.Val())
+		}
+	}
+
+	if c.Cron == "" || c.FileName == "" {
+		return c, d.Errf(`the "cron" and "file" subdirectives are required in the schedule block %q`, c.Name)
+	}
+
+	return c, nil
+}
+
+func parseOverlapPolicy(v string) (frankenphp.OverlapPolicy, error) {
+	switch v {
+	case "", "skip":
+		return frankenphp.OverlapSkip, nil
+	case "allow":
+		return frankenphp.OverlapAllow, nil
+	case "queue":
+		return frankenphp.OverlapQueue, nil
+	default:
+		return frankenphp.OverlapSkip, fmt.Errorf(`unknown overlap policy %q, expected "skip", "allow" or "queue"`, v)
+	}
+}
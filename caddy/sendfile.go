@@ -0,0 +1,98 @@
+package caddy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/dunglas/frankenphp"
+)
+
+// sendfileConfig represents the "sendfile" subdirective of the "php" and "php_server" directives
+//
+//	php_server {
+//		sendfile x-accel-redirect {
+//			root private-files/
+//			mapping ../private-files=/private-files
+//		}
+//	}
+type sendfileConfig struct {
+	// Type sets the response header containing the path of the file to send: "x-sendfile" or "x-accel-redirect"
+	Type string `json:"type,omitempty"`
+	// Root sets the directory the files are served from. Default: the root of the site.
+	Root string `json:"root,omitempty"`
+	// Mappings maps URI prefixes to directories, relative directories are resolved from the root of the site. They are also passed to PHP in the X-Accel-Mapping header.
+	Mappings map[string]string `json:"mappings,omitempty"`
+}
+
+func (c sendfileConfig) toRequestOption() (frankenphp.RequestOption, error) {
+	header, err := sendfileHeader(c.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	root := c.Root
+	if root != "" && frankenphp.EmbeddedAppPath != "" && filepath.IsLocal(root) {
+		root = filepath.Join(frankenphp.EmbeddedAppPath, root)
+	}
+
+	return frankenphp.WithRequestSendfile(header, root, c.Mappings), nil
+}
+
+func unmarshalSendfile(d *caddyfile.Dispenser) (*sendfileConfig, error) {
+	c := &sendfileConfig{}
+
+	if !d.NextArg() {
+		return nil, d.Err(`the "sendfile" directive requires a type (x-sendfile or x-accel-redirect)`)
+	}
+	if _, err := sendfileHeader(d.Val()); err != nil {
+		return nil, d.WrapErr(err)
+	}
+	c.Type = d.Val()
+
+	if d.NextArg() {
+		return nil, d.ArgErr()
+	}
+
+	for d.NextBlock(1) {
+		switch d.Val() {
+		case "root":
+			if !d.NextArg() {
+				return nil, d.ArgErr()
+			}
+			c.Root = d.Val()
+
+			if d.NextArg() {
+				return nil, d.ArgErr()
+			}
+		case "mapping":
+			for _, arg := range d.RemainingArgs() {
+				dir, prefix, ok := strings.Cut(arg, "=")
+				if !ok || dir == "" || prefix == "" {
+					return nil, d.Errf("invalid mapping %q, expected <directory>=<uri prefix> (example: ../private-files=/private-files)", arg)
+				}
+
+				if c.Mappings == nil {
+					c.Mappings = make(map[string]string)
+				}
+				c.Mappings[prefix] = dir
+			}
+		default:
+			return nil, wrongSubDirectiveError("sendfile", "root, mapping", d.Val())
+		}
+	}
+
+	return c, nil
+}
+
+func sendfileHeader(sendfileType string) (string, error) {
+	switch strings.ToLower(sendfileType) {
+	case "x-sendfile":
+		return "X-Sendfile", nil
+	case "x-accel-redirect":
+		return "X-Accel-Redirect", nil
+	default:
+		return "", fmt.Errorf(`unknown sendfile type %q, expected "x-sendfile" or "x-accel-redirect"`, sendfileType)
+	}
+}
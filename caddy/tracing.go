@@ -0,0 +1,155 @@
+package caddy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// tracingConfig represents the "tracing" block of the global "frankenphp" directive
+//
+//	{
+//		frankenphp {
+//			tracing {
+//				endpoint localhost:4317
+//				protocol grpc
+//				insecure
+//				sample_ratio 0.1
+//				service_name my-app
+//			}
+//		}
+//	}
+type tracingConfig struct {
+	// Endpoint of the OTLP collector. Default: the OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT environment variable
+	Endpoint string `json:"endpoint,omitempty"`
+	// Protocol used to send the spans: "grpc" (default) or "http"
+	Protocol string `json:"protocol,omitempty"`
+	// Insecure disables TLS when connecting to the collector
+	Insecure bool `json:"insecure,omitempty"`
+	// SampleRatio sets the ratio of new traces to record, traces started by the client or by Caddy follow their parent. Default: 1
+	SampleRatio *float64 `json:"sample_ratio,omitempty"`
+	// ServiceName sets the service.name resource attribute. Default: frankenphp
+	ServiceName string `json:"service_name,omitempty"`
+}
+
+// newTracerProvider creates a tracer provider sending the spans to the configured OTLP collector
+func (c *tracingConfig) newTracerProvider(ctx context.Context) (*sdktrace.TracerProvider, error) {
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+
+	switch c.Protocol {
+	case "", "grpc":
+		var opts []otlptracegrpc.Option
+		if strings.Contains(c.Endpoint, "://") {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(c.Endpoint))
+		} else if c.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(c.Endpoint))
+		}
+		if c.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	case "http":
+		var opts []otlptracehttp.Option
+		if strings.Contains(c.Endpoint, "://") {
+			opts = append(opts, otlptracehttp.WithEndpointURL(c.Endpoint))
+		} else if c.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(c.Endpoint))
+		}
+		if c.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown tracing protocol %q (allowed protocols are: grpc, http)", c.Protocol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the OTLP exporter: %w", err)
+	}
+
+	serviceName := c.ServiceName
+	if serviceName == "" {
+		serviceName = "frankenphp"
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	sampleRatio := 1.0
+	if c.SampleRatio != nil {
+		sampleRatio = *c.SampleRatio
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	), nil
+}
+
+func unmarshalTracing(d *caddyfile.Dispenser) (*tracingConfig, error) {
+	c := &tracingConfig{}
+
+	if d.NextArg() {
+		return nil, d.ArgErr()
+	}
+
+	for d.NextBlock(1) {
+		switch d.Val() {
+		case "endpoint":
+			if !d.NextArg() {
+				return nil, d.ArgErr()
+			}
+
+			c.Endpoint = d.Val()
+		case "protocol":
+			if !d.NextArg() {
+				return nil, d.ArgErr()
+			}
+
+			switch d.Val() {
+			case "grpc", "http":
+				c.Protocol = d.Val()
+			default:
+				return nil, d.Errf("unknown tracing protocol %q (allowed protocols are: grpc, http)", d.Val())
+			}
+		case "insecure":
+			c.Insecure = true
+		case "sample_ratio":
+			if !d.NextArg() {
+				return nil, d.ArgErr()
+			}
+
+			v, err := strconv.ParseFloat(d.Val(), 64)
+			if err != nil || v < 0 || v > 1 {
+				return nil, d.Err("sample_ratio must be a number between 0 and 1 (example: 0.1)")
+			}
+
+			c.SampleRatio = &v
+		case "service_name":
+			if !d.NextArg() {
+				return nil, d.ArgErr()
+			}
+
+			c.ServiceName = d.Val()
+		default:
+			return nil, wrongSubDirectiveError("tracing", "endpoint, protocol, insecure, sample_ratio, service_name", d.Val())
+		}
+	}
+
+	return c, nil
+}
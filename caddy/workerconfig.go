@@ -1,8 +1,11 @@
 package caddy
 
 import (
+	"fmt"
+	"net/http"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/caddyserver/caddy/v2"
 	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
@@ -38,10 +41,47 @@ type workerConfig struct {
 	MatchPath []string `json:"match_path,omitempty"`
 	// MaxConsecutiveFailures sets the maximum number of consecutive failures before panicking (defaults to 6, set to -1 to never panick)
 	MaxConsecutiveFailures int `json:"max_consecutive_failures,omitempty"`
+	// MaxQueueLength sets the maximum number of requests waiting for a thread of this worker. Default: the global max_queue
+	MaxQueueLength int `json:"max_queue_length,omitempty"`
+	// MaxMemory sets the memory usage in bytes above which a thread of this worker is restarted. Default: the global max_memory
+	MaxMemory int64 `json:"max_memory,omitempty"`
+	// RestartStrategy sets how threads are restarted on file changes or from the admin API: "all" (default) or "rolling"
+	RestartStrategy string `json:"restart_strategy,omitempty"`
+	// Subscribe lists the topics of frankenphp_publish() the worker receives
+	Subscribe []string `json:"subscribe,omitempty"`
+	// WebSocket makes the worker accept the WebSocket upgrades of the requests it handles
+	WebSocket bool `json:"websocket,omitempty"`
+	// WebSocketOrigins lists the origins allowed to connect in addition to the one of the request, "*" allows any origin
+	WebSocketOrigins []string `json:"websocket_origins,omitempty"`
+	// Background runs the script in its own loop instead of handling requests, see the "background_worker" directive
+	Background bool `json:"background,omitempty"`
+	// Priorities assign a priority to the requests matching a path, queued requests are served by priority
+	Priorities []workerPriorityConfig `json:"priorities,omitempty"`
 
 	options []frankenphp.WorkerOption
 }
 
+// workerPriorityConfig represents a "priority" block inside a "worker" directive
+//
+//	worker {
+//		file "my-worker.php"
+//		priority 10 {
+//			match /api/*
+//			weight 10
+//			max_wait_time 2s
+//		}
+//	}
+type workerPriorityConfig struct {
+	// Priority of the matching requests, unmatched requests have priority 0
+	Priority int `json:"priority,omitempty"`
+	// The path to match against the priority
+	MatchPath []string `json:"match_path,omitempty"`
+	// Weight sets the share of the worker threads given to this priority when requests are queued. Default: the priority itself.
+	Weight int `json:"weight,omitempty"`
+	// MaxWaitTime sets the maximum amount of time a request of this priority may be queued. Default: the global max_wait_time.
+	MaxWaitTime time.Duration `json:"max_wait_time,omitempty"`
+}
+
 func unmarshalWorker(d *caddyfile.Dispenser) (workerConfig, error) {
 	wc := workerConfig{}
 	if d.NextArg() {
@@ -139,8 +179,57 @@ func unmarshalWorker(d *caddyfile.Dispenser) (workerConfig, error) {
 			}
 
 			wc.MaxConsecutiveFailures = v
+		case "max_queue":
+			if !d.NextArg() {
+				return wc, d.ArgErr()
+			}
+
+			v, err := strconv.ParseUint(d.Val(), 10, 32)
+			if err != nil {
+				return wc, d.WrapErr(err)
+			}
+
+			wc.MaxQueueLength = int(v)
+		case "max_memory":
+			if !d.NextArg() {
+				return wc, d.ArgErr()
+			}
+
+			v, err := parseMemorySize(d.Val())
+			if err != nil {
+				return wc, d.WrapErr(err)
+			}
+
+			wc.MaxMemory = v
+		case "restart_strategy":
+			if !d.NextArg() {
+				return wc, d.ArgErr()
+			}
+
+			if _, err := parseRestartStrategy(d.Val()); err != nil {
+				return wc, d.WrapErr(err)
+			}
+
+			wc.RestartStrategy = d.Val()
+		case "subscribe":
+			topics := d.RemainingArgs()
+			if len(topics) == 0 {
+				return wc, d.ArgErr()
+			}
+
+			wc.Subscribe = append(wc.Subscribe, topics...)
+		case "websocket":
+			wc.WebSocket = true
+			wc.WebSocketOrigins = append(wc.WebSocketOrigins, d.RemainingArgs()...)
+		case "priority":
+			pc, err := unmarshalWorkerPriority(d)
+			if err != nil {
+				return wc, err
+			}
+
+			wc.Priorities = append(wc.Priorities, pc)
 		default:
-			return wc, wrongSubDirectiveError("worker", "name, file, num, env, watch, match, max_consecutive_failures, max_threads", v)
+			return wc, wrongSubDirectiveError("worker", "name, file, num, env, watch, match, max_consecutive_failures, max_threads, max_queue, max_memory, restart_strategy, subscribe, websocket, priority", v)
 		}
 	}
 
@@ -155,12 +244,110 @@ func unmarshalWorker(d *caddyfile.Dispenser) (workerConfig, error) {
 	return wc, nil
 }
 
+// unmarshalBackgroundWorker parses the "background_worker" directive, it accepts the same options as "worker"
+// except the ones related to requests
+func unmarshalBackgroundWorker(d *caddyfile.Dispenser) (workerConfig, error) {
+	wc, err := unmarshalWorker(d)
+	if err != nil {
+		return wc, err
+	}
+
+	if len(wc.MatchPath) > 0 || len(wc.Priorities) > 0 || len(wc.Subscribe) > 0 || wc.WebSocket {
+		return wc, d.Errf(`"match", "priority", "subscribe" and "websocket" cannot be used in a background_worker block: %q`, wc.FileName)
+	}
+	wc.Background = true
+
+	return wc, nil
+}
+
+func unmarshalWorkerPriority(d *caddyfile.Dispenser) (workerPriorityConfig, error) {
+	pc := workerPriorityConfig{}
+	if !d.NextArg() {
+		return pc, d.ArgErr()
+	}
+
+	v, err := strconv.Atoi(d.Val())
+	if err != nil {
+		return pc, d.WrapErr(err)
+	}
+	pc.Priority = v
+
+	if d.NextArg() {
+		return pc, d.Errf(`FrankenPHP: too many "priority" arguments: %s`, d.Val())
+	}
+
+	for d.NextBlock(2) {
+		switch v := d.Val(); v {
+		case "match":
+			caddyMatchPath := (caddyhttp.MatchPath)(d.RemainingArgs())
+			if err := caddyMatchPath.Provision(caddy.Context{}); err != nil {
+				return pc, d.WrapErr(err)
+			}
+
+			pc.MatchPath = caddyMatchPath
+		case "weight":
+			if !d.NextArg() {
+				return pc, d.ArgErr()
+			}
+
+			v, err := strconv.ParseUint(d.Val(), 10, 32)
+			if err != nil {
+				return pc, d.WrapErr(err)
+			}
+			if v < 1 {
+				return pc, d.Err("weight must be >= 1")
+			}
+
+			pc.Weight = int(v)
+		case "max_wait_time":
+			if !d.NextArg() {
+				return pc, d.ArgErr()
+			}
+
+			v, err := time.ParseDuration(d.Val())
+			if err != nil {
+				return pc, d.Err("max_wait_time must be a valid duration (example: 10s)")
+			}
+
+			pc.MaxWaitTime = v
+		default:
+			return pc, wrongSubDirectiveError("priority", "match, weight, max_wait_time", v)
+		}
+	}
+
+	if len(pc.MatchPath) == 0 {
+		return pc, d.Err(`the "match" subdirective of "priority" must be specified`)
+	}
+
+	return pc, nil
+}
+
 func (wc *workerConfig) toWorkerOptions() ([]frankenphp.WorkerOption, error) {
 	opts := []frankenphp.WorkerOption{
 		frankenphp.WithWorkerEnv(wc.Env),
 		frankenphp.WithWorkerWatchMode(wc.Watch),
 		frankenphp.WithWorkerMaxFailures(wc.MaxConsecutiveFailures),
 		frankenphp.WithWorkerMaxThreads(wc.MaxThreads),
+		frankenphp.WithWorkerMaxQueueLength(wc.MaxQueueLength),
+		frankenphp.WithWorkerMaxThreadMemory(wc.MaxMemory),
+		frankenphp.WithWorkerSubscriptions(wc.Subscribe...),
+	}
+
+	if wc.Background {
+		opts = append(opts, frankenphp.WithWorkerBackground())
+	}
+
+	if wc.WebSocket {
+		opts = append(opts, frankenphp.WithWorkerWebSocket(wc.WebSocketOrigins...))
+	}
+
+	if wc.RestartStrategy != "" {
+		strategy, err := parseRestartStrategy(wc.RestartStrategy)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, frankenphp.WithWorkerRestartStrategy(strategy))
 	}
 
 	// options collected while provisioning the module, e.g. the Mercure hub
@@ -175,5 +362,61 @@ func (wc *workerConfig) toWorkerOptions() ([]frankenphp.WorkerOption, error) {
 		}
 		opts = append(opts, frankenphp.WithWorkerMatcher(matchFunc.Match))
 	}
+
+	if len(wc.Priorities) > 0 {
+		priorityOpts, err := wc.toPriorityOptions()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, priorityOpts...)
+	}
+
 	return opts, nil
 }
+
+// toPriorityOptions creates a priority function from the "priority" blocks, the first matching block wins
+func (wc *workerConfig) toPriorityOptions() ([]frankenphp.WorkerOption, error) {
+	matchers := make([]caddyhttp.MatchPath, len(wc.Priorities))
+	opts := make([]frankenphp.WorkerOption, 0, len(wc.Priorities)+1)
+	for i, pc := range wc.Priorities {
+		matchers[i] = append(caddyhttp.MatchPath(nil), pc.MatchPath...)
+		if err := matchers[i].Provision(caddy.Context{}); err != nil {
+			return nil, err
+		}
+
+		weight := pc.Weight
+		if weight == 0 {
+			weight = max(pc.Priority, 1)
+		}
+		opts = append(opts, frankenphp.WithWorkerPriorityClass(pc.Priority, weight, pc.MaxWaitTime))
+	}
+
+	priorities := wc.Priorities
+	opts = append(opts, frankenphp.WithWorkerRequestPriority(func(r *http.Request) int {
+		// match against the original request, the php_server directive may have rewritten the path
+		if originalRequest, ok := r.Context().Value(caddyhttp.OriginalRequestCtxKey).(http.Request); ok {
+			r = &originalRequest
+		}
+
+		for i, m := range matchers {
+			if m.Match(r) {
+				return priorities[i].Priority
+			}
+		}
+
+		return 0
+	}))
+
+	return opts, nil
+}
+
+func parseRestartStrategy(v string) (frankenphp.RestartStrategy, error) {
+	switch v {
+	case "all":
+		return frankenphp.RestartStrategyAll, nil
+	case "rolling":
+		return frankenphp.RestartStrategyRolling, nil
+	default:
+		return frankenphp.RestartStrategyDefault, fmt.Errorf(`unknown restart strategy %q, expected "all" or "rolling"`, v)
+	}
+}
@@ -178,6 +178,7 @@ func go_register_server_variables(threadIndex C.uintptr_t, trackVarsArray *C.zva
 
 	addKnownVariablesToServer(fc, trackVarsArray)
 	addHeadersToServer(fc.ctx, fc.request, trackVarsArray)
+	addTraceContextToServer(fc, trackVarsArray)
 
 	// The Prepared Environment is registered last and can overwrite any previous values
 	if len(fc.env) != 0 || len(fc.server.env) != 0 {
@@ -319,6 +320,17 @@ func go_update_request_info(threadIndex C.uintptr_t, info *C.sapi_request_info)
 
 	info.proto_num = C.int(request.ProtoMajor*1000 + request.ProtoMinor)
 
+	if len(fc.args) > 0 {
+		argv := make([]*C.char, len(fc.args))
+		for i, arg := range fc.args {
+			argv[i] = thread.pinCString(arg)
+		}
+		thread.Pin(&argv[0])
+
+		info.argc = C.int(len(argv))
+		info.argv = &argv[0]
+	}
+
 	authorizationHeader := request.Header.Get("Authorization")
 	if authorizationHeader == "" {
 		return nil
@@ -5,12 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // frankenPHPContext provides contextual information about the Request to handle.
@@ -28,6 +33,48 @@ type frankenPHPContext struct {
 
 	// idle timeout per body read; zero disables it
 	requestBodyTimeout time.Duration
+	// wall clock budget of the PHP execution; zero disables it
+	requestTimeout time.Duration
+	timeoutMu      sync.Mutex
+	timeoutTimer   *time.Timer
+	timedOut       atomic.Bool
+	// whether the request has already been reported by the slow log
+	slowlogged atomic.Bool
+	// status code sent to the client, 0 if no response has been sent yet
+	status int
+	// time spent waiting for a free thread
+	queueWait time.Duration
+	// set for the runs of scheduled tasks, which are not HTTP requests
+	isScheduledTask bool
+	// $argv of scheduled tasks, empty for HTTP requests
+	args []string
+	// exit status of the script, only set for regular threads
+	exitStatus int
+	// X-Sendfile/X-Accel-Redirect configuration, nil if disabled
+	sendfile *sendfile
+	// file to send in place of the output of the script, set when the sendfile header is detected
+	sendfilePath string
+	// file passed to frankenphp_send_file(), nil if none
+	sentFile *sentFile
+	// size of the buffer of the detached output mode, 0 if the output is written synchronously
+	outputBufferSize int
+	// buffered output drained to the client by a goroutine, nil until the script writes in detached mode
+	output *detachedOutput
+	// channel passed to frankenphp_sse_subscribe(), empty if none
+	sseChannel string
+	// registered by frankenphp_sse_subscribe(), buffers the events published until the thread is released
+	sseSubscriber *sseSubscriber
+	// events missed by the client since the Last-Event-ID header, sent before the buffered ones
+	sseReplay []sseEvent
+	// context waiting for the return value of frankenphp_send_message(), nil if the context does not handle a message
+	messageSender *frankenPHPContext
+	// time the request may wait for a thread if max_wait_time is disabled, zero to wait forever
+	fallbackMaxWaitTime time.Duration
+
+	// time spent waiting for a thread, nil if tracing is disabled or the request was not queued
+	queueSpan trace.Span
+	// active spans of the script, the innermost last
+	spans []trace.Span
 
 	docURI         string
 	pathInfo       string
@@ -53,6 +100,8 @@ type frankenPHPContext struct {
 
 	done      chan any
 	startedAt time.Time
+	// when a thread started handling the request, set before the thread publishes the context
+	executionStartedAt time.Time
 }
 
 // NewRequestWithContext creates a new FrankenPHP request context.
@@ -181,12 +230,67 @@ func newContextFromMessage(message any, rw http.ResponseWriter, ctx context.Cont
 	}
 }
 
+// getMaxWaitTime returns how long the request may wait for a thread, zero to wait forever
+func (fc *frankenPHPContext) getMaxWaitTime(maxWaitTime time.Duration) time.Duration {
+	if maxWaitTime == 0 {
+		return fc.fallbackMaxWaitTime
+	}
+
+	return maxWaitTime
+}
+
+// startRequestTimeout interrupts the PHP execution on the given thread if it exceeds the request timeout
+func (fc *frankenPHPContext) startRequestTimeout(thread *phpThread) {
+	if fc.requestTimeout <= 0 {
+		return
+	}
+
+	fc.timeoutMu.Lock()
+	defer fc.timeoutMu.Unlock()
+
+	fc.timeoutTimer = time.AfterFunc(fc.requestTimeout, func() {
+		fc.timeoutMu.Lock()
+		defer fc.timeoutMu.Unlock()
+
+		// the request finished in the meantime, the thread may already be handling another one
+		if fc.timeoutTimer == nil {
+			return
+		}
+		fc.timeoutTimer = nil
+		fc.timedOut.Store(true)
+
+		if fc.logger.Enabled(fc.ctx, slog.LevelWarn) {
+			fc.logger.LogAttrs(fc.ctx, slog.LevelWarn, "request timeout exceeded, interrupting PHP execution",
+				slog.String("script", fc.scriptFilename),
+				slog.String("uri", fc.requestURI),
+				slog.Int("thread", thread.threadIndex),
+				slog.Duration("timeout", fc.requestTimeout),
+			)
+		}
+
+		thread.sendKillSignal()
+	})
+}
+
+// stopRequestTimeout disarms the request timeout once PHP is done with the request
+func (fc *frankenPHPContext) stopRequestTimeout() {
+	fc.timeoutMu.Lock()
+	defer fc.timeoutMu.Unlock()
+
+	if fc.timeoutTimer != nil {
+		fc.timeoutTimer.Stop()
+		fc.timeoutTimer = nil
+	}
+}
+
 // closeContext sends the response to the client
 func (fc *frankenPHPContext) closeContext() {
 	if fc.isDone {
 		return
 	}
 
+	fc.stopRequestTimeout()
+
 	// Snapshot before close(fc.done): that call is what eventually lets the
 	// handler return and cancels request.Context(), so clientHasClosed()
 	// must be read before it, not after.
@@ -195,6 +299,23 @@ func (fc *frankenPHPContext) closeContext() {
 	fc.isDone = true
 }
 
+// stats returns the statistics of the request reported to the metrics
+func (fc *frankenPHPContext) stats() RequestStats {
+	s := RequestStats{
+		Status:    fc.status,
+		Duration:  time.Since(fc.startedAt),
+		QueueWait: fc.queueWait,
+	}
+	if fc.server != nil {
+		s.Server = fc.server.Name()
+	}
+	if fc.worker != nil {
+		s.Worker = fc.worker.name
+	}
+
+	return s
+}
+
 // validate checks if the request should be outright rejected
 func (fc *frankenPHPContext) validate() error {
 	if strings.Contains(fc.request.URL.Path, "\x00") {
@@ -244,7 +365,12 @@ func (fc *frankenPHPContext) reject(err error) {
 
 	rw := fc.responseWriter
 	if rw != nil {
+		if re.retryAfter > 0 {
+			rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(re.retryAfter.Seconds()))))
+		}
+
 		rw.WriteHeader(re.status)
+		fc.status = re.status
 		_, _ = rw.Write([]byte(err.Error()))
 
 		if f, ok := rw.(http.Flusher); ok {
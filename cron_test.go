@@ -0,0 +1,67 @@
+package frankenphp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronNext(t *testing.T) {
+	// Wednesday
+	now := time.Date(2025, time.January, 15, 10, 30, 20, 0, time.UTC)
+
+	for expression, expected := range map[string]time.Time{
+		"* * * * *":          time.Date(2025, time.January, 15, 10, 31, 0, 0, time.UTC),
+		"*/15 * * * *":       time.Date(2025, time.January, 15, 10, 45, 0, 0, time.UTC),
+		"0 * * * *":          time.Date(2025, time.January, 15, 11, 0, 0, 0, time.UTC),
+		"30 10 * * *":        time.Date(2025, time.January, 16, 10, 30, 0, 0, time.UTC),
+		"0 9-17/4 * * *":     time.Date(2025, time.January, 15, 13, 0, 0, 0, time.UTC),
+		"0 0 * * mon":        time.Date(2025, time.January, 20, 0, 0, 0, 0, time.UTC),
+		"0 0 * * 7":          time.Date(2025, time.January, 19, 0, 0, 0, 0, time.UTC),
+		"0 0 1 feb *":        time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC),
+		"0 0 20 * fri":       time.Date(2025, time.January, 17, 0, 0, 0, 0, time.UTC),
+		"0 0 29 2 *":         time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC),
+		"5,10 3 * * *":       time.Date(2025, time.January, 16, 3, 5, 0, 0, time.UTC),
+		"@daily":             time.Date(2025, time.January, 16, 0, 0, 0, 0, time.UTC),
+		"@hourly":            time.Date(2025, time.January, 15, 11, 0, 0, 0, time.UTC),
+		"@yearly":            time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+		"45/5 10 15 jan wed": time.Date(2025, time.January, 15, 10, 45, 0, 0, time.UTC),
+		"0 0 20 * 0-6":       time.Date(2025, time.January, 20, 0, 0, 0, 0, time.UTC),
+		"0 0 1-31 * mon":     time.Date(2025, time.January, 20, 0, 0, 0, 0, time.UTC),
+	} {
+		t.Run(expression, func(t *testing.T) {
+			s, err := parseCron(expression)
+			require.NoError(t, err)
+			assert.Equal(t, expected, s.next(now))
+		})
+	}
+}
+
+func TestCronNextNever(t *testing.T) {
+	s, err := parseCron("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.next(time.Now()).IsZero())
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"* * * foo *",
+		"@every 5m",
+	} {
+		t.Run(expression, func(t *testing.T) {
+			_, err := parseCron(expression)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -3,27 +3,57 @@ package frankenphp
 // #include "frankenphp.h"
 import "C"
 import (
+	"time"
+
 	"github.com/dunglas/frankenphp/internal/state"
 )
 
 // EXPERIMENTAL: ThreadDebugState prints the state of a single PHP thread - debugging purposes only
 type ThreadDebugState struct {
-	Index                    int
-	Name                     string
-	State                    string
-	IsWaiting                bool
-	IsBusy                   bool
-	WaitingSinceMilliseconds int64
-	CurrentURI               string
-	CurrentMethod            string
-	RequestStartedAt         int64
-	RequestCount             int64
-	MemoryUsage              int64
+	Index                              int
+	Name                               string
+	State                              string
+	WorkerName                         string
+	ServerName                         string
+	IsWaiting                          bool
+	IsBusy                             bool
+	WaitingSinceMilliseconds           int64
+	CurrentURI                         string
+	CurrentMethod                      string
+	RemoteAddr                         string
+	RequestStartedAt                   int64
+	CurrentRequestDurationMilliseconds int64
+	RequestCount                       int64
+	MemoryUsage                        int64
+	PeakMemoryUsage                    int64
+	ConsecutiveFailures                int
+}
+
+// EXPERIMENTAL: WorkerDebugState prints an aggregated summary of a worker - debugging purposes only
+type WorkerDebugState struct {
+	Name                   string
+	ServerName             string
+	BusyThreads            int
+	IdleThreads            int
+	QueuedRequests         int
+	LatencyP50Milliseconds int64
+	LatencyP99Milliseconds int64
+}
+
+// EXPERIMENTAL: ServerDebugState prints the thread usage of a server - debugging purposes only
+type ServerDebugState struct {
+	Name           string
+	BusyThreads    int
+	MinThreads     int
+	MaxThreads     int
+	QueuedRequests int
 }
 
 // EXPERIMENTAL: FrankenPHPDebugState prints the state of all PHP threads - debugging purposes only
 type FrankenPHPDebugState struct {
 	ThreadDebugStates   []ThreadDebugState
+	WorkerDebugStates   []WorkerDebugState
+	ServerDebugStates   []ServerDebugState
 	ReservedThreadCount int
 }
 
@@ -31,6 +61,8 @@ type FrankenPHPDebugState struct {
 func DebugState() FrankenPHPDebugState {
 	fullState := FrankenPHPDebugState{
 		ThreadDebugStates:   make([]ThreadDebugState, 0, len(phpThreads)),
+		WorkerDebugStates:   make([]WorkerDebugState, 0, len(workers)),
+		ServerDebugStates:   make([]ServerDebugState, 0, len(servers)),
 		ReservedThreadCount: 0,
 	}
 	for _, thread := range phpThreads {
@@ -41,6 +73,20 @@ func DebugState() FrankenPHPDebugState {
 		fullState.ThreadDebugStates = append(fullState.ThreadDebugStates, threadDebugState(thread))
 	}
 
+	for _, w := range workers {
+		fullState.WorkerDebugStates = append(fullState.WorkerDebugStates, workerDebugState(w))
+	}
+
+	for _, s := range servers {
+		fullState.ServerDebugStates = append(fullState.ServerDebugStates, ServerDebugState{
+			Name:           s.name,
+			BusyThreads:    int(s.busyThreads.Load()),
+			MinThreads:     s.minThreads,
+			MaxThreads:     s.maxThreads,
+			QueuedRequests: int(s.queuedRequests.Load()),
+		})
+	}
+
 	return fullState
 }
 
@@ -59,10 +105,7 @@ func threadDebugState(thread *phpThread) ThreadDebugState {
 
 	s.RequestCount = thread.requestCount.Load()
 	s.MemoryUsage = int64(C.frankenphp_get_thread_memory_usage(C.uintptr_t(thread.threadIndex)))
-
-	if !isBusy {
-		return s
-	}
+	s.PeakMemoryUsage = int64(C.frankenphp_get_thread_peak_memory_usage(C.uintptr_t(thread.threadIndex)))
 
 	thread.handlerMu.RLock()
 	handler := thread.handler
@@ -72,6 +115,22 @@ func threadDebugState(thread *phpThread) ThreadDebugState {
 		return s
 	}
 
+	if wt, ok := handler.(*workerThread); ok {
+		s.WorkerName = wt.worker.name
+		if wt.worker.server != nil {
+			s.ServerName = wt.worker.server.Name()
+		}
+		s.ConsecutiveFailures = int(wt.failureCount.Load())
+	}
+
+	if rt, ok := handler.(*regularThread); ok && rt.server != nil {
+		s.ServerName = rt.server.Name()
+	}
+
+	if !isBusy {
+		return s
+	}
+
 	thread.contextMu.RLock()
 	defer thread.contextMu.RUnlock()
 
@@ -82,10 +141,40 @@ func threadDebugState(thread *phpThread) ThreadDebugState {
 
 	s.CurrentURI = fc.requestURI
 	s.CurrentMethod = fc.request.Method
+	s.RemoteAddr = fc.request.RemoteAddr
+	if fc.server != nil && s.ServerName == "" {
+		s.ServerName = fc.server.Name()
+	}
 
 	if !fc.startedAt.IsZero() {
 		s.RequestStartedAt = fc.startedAt.UnixMilli()
+		s.CurrentRequestDurationMilliseconds = time.Since(fc.startedAt).Milliseconds()
+	}
+
+	return s
+}
+
+// workerDebugState aggregates the state of the threads of a worker
+func workerDebugState(w *worker) WorkerDebugState {
+	s := WorkerDebugState{
+		Name:                   w.name,
+		QueuedRequests:         int(w.queuedRequests.Load()),
+		LatencyP50Milliseconds: w.requestDurations.percentile(0.5).Milliseconds(),
+		LatencyP99Milliseconds: w.requestDurations.percentile(0.99).Milliseconds(),
+	}
+	if w.server != nil {
+		s.ServerName = w.server.Name()
+	}
+
+	w.threadMutex.RLock()
+	for _, thread := range w.threads {
+		if thread.state.IsInWaitingState() {
+			s.IdleThreads++
+		} else {
+			s.BusyThreads++
+		}
 	}
+	w.threadMutex.RUnlock()
 
 	return s
 }
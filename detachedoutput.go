@@ -0,0 +1,204 @@
+package frankenphp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// defaultDetachedOutputBufferSize is the default size of the buffer of the detached output mode
+const defaultDetachedOutputBufferSize = 1 << 20
+
+// WithRequestDetachedOutput makes the script write its output into a buffer of at most bufferSize bytes
+// (1MB if 0) drained to the client by a goroutine, instead of writing it to the client synchronously.
+// The PHP thread is released as soon as the script has finished, even if a slow client hasn't read the
+// whole response yet. The thread only waits for the client when the buffer is full.
+func WithRequestDetachedOutput(bufferSize int) RequestOption {
+	if bufferSize <= 0 {
+		bufferSize = defaultDetachedOutputBufferSize
+	}
+
+	return func(fc *frankenPHPContext) error {
+		fc.outputBufferSize = bufferSize
+
+		return nil
+	}
+}
+
+// detachedOutput is the output of a script buffered in memory and drained to the client by a goroutine
+type detachedOutput struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+
+	mu sync.Mutex
+	// signaled when data is buffered or drained, and when the output is closed or fails
+	cond    *sync.Cond
+	chunks  [][]byte
+	size    int
+	maxSize int
+	// whether a flush has been requested
+	flush bool
+	// whether the script won't write anymore
+	closed bool
+	// error writing to the client, further writes are discarded
+	err error
+
+	// stops waking up the writers when the request context is canceled
+	stopAfterFunc func() bool
+	// closed when the drain goroutine returns
+	done chan struct{}
+}
+
+func newDetachedOutput(ctx context.Context, w http.ResponseWriter, maxSize int) *detachedOutput {
+	o := &detachedOutput{
+		w:       w,
+		rc:      http.NewResponseController(w),
+		maxSize: maxSize,
+		done:    make(chan struct{}),
+	}
+	o.cond = sync.NewCond(&o.mu)
+
+	// the client is gone, don't make the thread wait for the buffer to be drained
+	o.stopAfterFunc = context.AfterFunc(ctx, func() {
+		o.fail(ctx.Err())
+	})
+
+	metrics.StartDetachedResponse()
+	go o.drain()
+
+	return o
+}
+
+// outputBuffer returns the detached output of the request, it is created on first use
+func (fc *frankenPHPContext) outputBuffer() *detachedOutput {
+	if fc.output == nil {
+		fc.output = newDetachedOutput(fc.ctx, fc.responseWriter, fc.outputBufferSize)
+	}
+
+	return fc.output
+}
+
+// write copies p into the buffer, it waits for the buffer to have room when it is full
+func (o *detachedOutput) write(p []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// a chunk larger than the buffer is accepted once the buffer is empty
+	for o.err == nil && o.size > 0 && o.size+len(p) > o.maxSize {
+		o.cond.Wait()
+	}
+
+	if o.err != nil {
+		return o.err
+	}
+
+	o.chunks = append(o.chunks, bytes.Clone(p))
+	o.size += len(p)
+	metrics.DetachedOutputBuffered(len(p))
+	o.cond.Broadcast()
+
+	return nil
+}
+
+// requestFlush makes the drain goroutine flush the response once the buffered data has been written
+func (o *detachedOutput) requestFlush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.flush = true
+	o.cond.Broadcast()
+}
+
+func (o *detachedOutput) fail(err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.err == nil {
+		o.err = err
+	}
+	o.cond.Broadcast()
+}
+
+// close waits for the buffered data to be sent to the client, it must be called once the script doesn't write anymore
+func (o *detachedOutput) close() error {
+	o.mu.Lock()
+	o.closed = true
+	o.cond.Broadcast()
+	o.mu.Unlock()
+
+	<-o.done
+	o.stopAfterFunc()
+	metrics.StopDetachedResponse()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.err
+}
+
+func (o *detachedOutput) drain() {
+	defer close(o.done)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for {
+		for len(o.chunks) == 0 && !o.flush && !o.closed && o.err == nil {
+			o.cond.Wait()
+		}
+
+		if o.err != nil || (o.closed && len(o.chunks) == 0 && !o.flush) {
+			break
+		}
+
+		chunks, size, flush := o.chunks, o.size, o.flush
+		o.chunks, o.flush = nil, false
+		o.mu.Unlock()
+
+		err := o.writeChunks(chunks, flush)
+
+		o.mu.Lock()
+		o.size -= size
+		metrics.DetachedOutputBuffered(-size)
+		if err != nil && o.err == nil {
+			o.err = err
+		}
+		o.cond.Broadcast()
+	}
+
+	// discard what can't be sent anymore
+	metrics.DetachedOutputBuffered(-o.size)
+	o.chunks, o.size = nil, 0
+}
+
+func (o *detachedOutput) writeChunks(chunks [][]byte, flush bool) error {
+	for _, c := range chunks {
+		if _, err := o.w.Write(c); err != nil {
+			return err
+		}
+	}
+
+	if !flush {
+		return nil
+	}
+
+	if err := o.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	return nil
+}
+
+// closeDetachedOutput waits for the detached output of the request to be sent, if any
+func (fc *frankenPHPContext) closeDetachedOutput() {
+	if fc.output == nil {
+		return
+	}
+
+	if err := fc.output.close(); err != nil && fc.logger.Enabled(fc.ctx, slog.LevelDebug) {
+		fc.logger.LogAttrs(fc.ctx, slog.LevelDebug, "unable to send the detached output", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,82 @@
+package frankenphp
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowResponseWriter blocks every write until release is closed
+type slowResponseWriter struct {
+	*httptest.ResponseRecorder
+	release chan struct{}
+}
+
+func (w *slowResponseWriter) Write(buf []byte) (int, error) {
+	<-w.release
+
+	return w.ResponseRecorder.Write(buf)
+}
+
+func TestDetachedOutput(t *testing.T) {
+	w := httptest.NewRecorder()
+	o := newDetachedOutput(t.Context(), w, 1024)
+
+	require.NoError(t, o.write([]byte("Hello ")))
+	o.requestFlush()
+	require.NoError(t, o.write([]byte("World")))
+	require.NoError(t, o.close())
+
+	assert.Equal(t, "Hello World", w.Body.String())
+	assert.True(t, w.Flushed)
+}
+
+func TestDetachedOutputWaitsWhenFull(t *testing.T) {
+	w := &slowResponseWriter{httptest.NewRecorder(), make(chan struct{})}
+	o := newDetachedOutput(t.Context(), w, 4)
+
+	// a chunk larger than the buffer is accepted if the buffer is empty
+	require.NoError(t, o.write([]byte("Hello")))
+
+	written := make(chan error)
+	go func() {
+		written <- o.write([]byte(" World"))
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("the write must wait for the buffer to be drained")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(w.release)
+	require.NoError(t, <-written)
+	require.NoError(t, o.close())
+
+	assert.Equal(t, "Hello World", w.Body.String())
+}
+
+func TestDetachedOutputClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	w := &slowResponseWriter{httptest.NewRecorder(), make(chan struct{})}
+	o := newDetachedOutput(ctx, w, 4)
+
+	require.NoError(t, o.write([]byte("Hello")))
+
+	written := make(chan error)
+	go func() {
+		written <- o.write([]byte(" World"))
+	}()
+
+	// the writer doesn't wait for the buffer to be drained anymore
+	cancel()
+	require.ErrorIs(t, <-written, context.Canceled)
+
+	close(w.release)
+	require.ErrorIs(t, o.close(), context.Canceled)
+	assert.NotContains(t, w.Body.String(), "World")
+}
@@ -115,6 +115,7 @@ frankenphp_config frankenphp_get_config() {
 
 bool should_filter_var = 0;
 bool original_user_abort_setting = 0;
+bool original_register_argc_argv = 0;
 frankenphp_interned_strings_t frankenphp_strings = {0};
 HashTable *main_thread_env = NULL;
 
@@ -126,11 +127,15 @@ HashTable *main_thread_env = NULL;
 
 static THREAD_LOCAL uintptr_t thread_index;
 static THREAD_LOCAL bool is_worker_thread = false;
+static THREAD_LOCAL bool is_background_worker_thread = false;
 static THREAD_LOCAL HashTable *sandboxed_env = NULL;
 /* prepared_env holds entries from php(_server)'s `env KEY VAL`, exposed to
  * getenv() and merged into $_ENV when 'E' is in variables_order. Separate from
  * putenv() so those don't leak into $_ENV. */
 static THREAD_LOCAL HashTable *prepared_env = NULL;
+/* Number of entries of EG(included_files) of the worker script already
+ * reported to Go, see frankenphp_report_included_files() */
+static THREAD_LOCAL uint32_t reported_included_files = 0;
 
 /* Published via SG(server_context) so ext-parallel children, which inherit
  * the parent's SG(server_context), can route SAPI callbacks back to the
@@ -342,8 +347,10 @@ void frankenphp_release_thread_for_kill(force_kill_slot slot) {
 #endif
 }
 
-void frankenphp_update_local_thread_context(bool is_worker) {
+void frankenphp_update_local_thread_context(bool is_worker,
+                                           bool is_background_worker) {
   is_worker_thread = is_worker;
+  is_background_worker_thread = is_background_worker;
 
   /* workers should keep running if the user aborts the connection */
   PG(ignore_user_abort) = is_worker ? 1 : original_user_abort_setting;
@@ -360,6 +367,10 @@ static void frankenphp_update_request_context() {
   char *authorization_header =
       go_update_request_info(thread_index, &SG(request_info));
 
+  /* scheduled tasks get their arguments in $argv, like CLI scripts */
+  PG(register_argc_argv) =
+      SG(request_info).argc > 0 ? 1 : original_register_argc_argv;
+
   /* let PHP handle basic auth */
   php_handle_auth_data(authorization_header);
 }
@@ -376,6 +387,8 @@ static void frankenphp_free_request_context() {
   SG(request_info).content_type = NULL;
   SG(request_info).path_translated = NULL;
   SG(request_info).request_uri = NULL;
+  SG(request_info).argc = 0;
+  SG(request_info).argv = NULL;
 }
 
 /* reset all 'auto globals' in worker mode except of $_ENV
@@ -502,10 +515,163 @@ static void frankenphp_reset_session_state(void) {
 
 static frankenphp_thread_metrics *thread_metrics = NULL;
 
+/* Backtraces are captured from the PHP thread itself in the VM interrupt
+ * handler, where the current execute_data is safe to walk. */
+#define FRANKENPHP_MAX_BACKTRACE_FRAMES 64
+
+static void (*original_interrupt_function)(zend_execute_data *execute_data) =
+    NULL;
+
+static void frankenphp_report_backtrace(zend_execute_data *execute_data) {
+  frankenphp_stack_frame frames[FRANKENPHP_MAX_BACKTRACE_FRAMES];
+  int count = 0;
+
+  for (zend_execute_data *ex = execute_data;
+       ex != NULL && count < FRANKENPHP_MAX_BACKTRACE_FRAMES;
+       ex = ex->prev_execute_data) {
+    zend_function *func = ex->func;
+    if (func == NULL) {
+      continue;
+    }
+
+    frankenphp_stack_frame *frame = &frames[count++];
+    memset(frame, 0, sizeof(*frame));
+
+    if (func->common.scope != NULL) {
+      frame->class_name = ZSTR_VAL(func->common.scope->name);
+      frame->class_name_len = ZSTR_LEN(func->common.scope->name);
+    }
+
+    if (func->common.function_name != NULL) {
+      frame->function = ZSTR_VAL(func->common.function_name);
+      frame->function_len = ZSTR_LEN(func->common.function_name);
+    } else {
+      frame->function = "{main}";
+      frame->function_len = sizeof("{main}") - 1;
+    }
+
+    if (ZEND_USER_CODE(func->type)) {
+      frame->file = ZSTR_VAL(func->op_array.filename);
+      frame->file_len = ZSTR_LEN(func->op_array.filename);
+      if (ex->opline != NULL) {
+        frame->line = ex->opline->lineno;
+      }
+    }
+  }
+
+  go_frankenphp_report_backtrace(thread_index, frames, count);
+}
+
+static void frankenphp_interrupt_function(zend_execute_data *execute_data) {
+  if (__atomic_exchange_n(&thread_metrics[thread_index].backtrace_requested,
+                          false, __ATOMIC_RELAXED)) {
+    frankenphp_report_backtrace(execute_data);
+  }
+
+  if (original_interrupt_function != NULL) {
+    original_interrupt_function(execute_data);
+  }
+}
+
+void frankenphp_request_backtrace(force_kill_slot slot, uintptr_t idx) {
+  if (slot.vm_interrupt == NULL) {
+    /* Thread not booted yet */
+    return;
+  }
+
+  __atomic_store_n(&thread_metrics[idx].backtrace_requested, true,
+                   __ATOMIC_RELAXED);
+  zend_atomic_bool_store(slot.vm_interrupt, true);
+}
+
+void frankenphp_request_opcache_invalidation(uintptr_t idx) {
+  __atomic_store_n(&thread_metrics[idx].opcache_invalidation_requested, true,
+                   __ATOMIC_RELAXED);
+}
+
+void frankenphp_invalidate_script(const char *file, size_t len) {
+  zend_function *invalidate =
+      zend_hash_str_find_ptr(CG(function_table), "opcache_invalidate",
+                             sizeof("opcache_invalidate") - 1);
+  if (invalidate == NULL) {
+    /* opcache is not loaded */
+    return;
+  }
+
+  zval params[2], retval;
+  ZVAL_STRINGL(&params[0], file, len);
+  ZVAL_TRUE(&params[1]);
+  zend_call_known_function(invalidate, NULL, NULL, &retval, 2, params, NULL);
+  zval_ptr_dtor(&params[0]);
+  zval_ptr_dtor(&retval);
+}
+
+void frankenphp_invalidate_all_scripts(void) {
+  zend_function *get_status =
+      zend_hash_str_find_ptr(CG(function_table), "opcache_get_status",
+                             sizeof("opcache_get_status") - 1);
+  if (get_status == NULL) {
+    /* opcache is not loaded */
+    return;
+  }
+
+  zval param, status;
+  ZVAL_TRUE(&param);
+  zend_call_known_function(get_status, NULL, NULL, &status, 1, &param, NULL);
+
+  /* opcache_get_status() returns false if the opcache is disabled */
+  if (Z_TYPE(status) == IS_ARRAY) {
+    zval *scripts =
+        zend_hash_str_find(Z_ARRVAL(status), "scripts", sizeof("scripts") - 1);
+    if (scripts != NULL && Z_TYPE_P(scripts) == IS_ARRAY) {
+      zend_string *file;
+      ZEND_HASH_FOREACH_STR_KEY(Z_ARRVAL_P(scripts), file) {
+        if (file != NULL) {
+          frankenphp_invalidate_script(ZSTR_VAL(file), ZSTR_LEN(file));
+        }
+      }
+      ZEND_HASH_FOREACH_END();
+    }
+  }
+  zval_ptr_dtor(&status);
+}
+
+/* Reports the files included by the worker script since the last call, so
+ * that they can be invalidated from the opcache when the worker restarts */
+static void frankenphp_report_included_files(void) {
+  uint32_t count = zend_hash_num_elements(&EG(included_files));
+  if (count <= reported_included_files) {
+    return;
+  }
+
+  uint32_t i = 0;
+  zend_string *file;
+  ZEND_HASH_MAP_FOREACH_STR_KEY(&EG(included_files), file) {
+    if (i++ >= reported_included_files && file != NULL) {
+      go_frankenphp_worker_included_file(thread_index, ZSTR_VAL(file),
+                                         ZSTR_LEN(file));
+    }
+  }
+  ZEND_HASH_FOREACH_END();
+
+  reported_included_files = count;
+}
+
+void frankenphp_clear_kill_signal(void) {
+  zend_atomic_bool_store(&EG(timed_out), false);
+
+  if (!__atomic_load_n(&thread_metrics[thread_index].backtrace_requested,
+                       __ATOMIC_RELAXED)) {
+    zend_atomic_bool_store(&EG(vm_interrupt), false);
+  }
+}
+
 /* Adapted from php_request_shutdown */
 static void frankenphp_worker_request_shutdown() {
   __atomic_store_n(&thread_metrics[thread_index].last_memory_usage,
                    zend_memory_usage(0), __ATOMIC_RELAXED);
+  __atomic_store_n(&thread_metrics[thread_index].last_peak_memory_usage,
+                   zend_memory_peak_usage(0), __ATOMIC_RELAXED);
 
   /* Flush all output buffers */
   zend_try { php_output_end_all(); }
@@ -922,6 +1088,7 @@ PHP_FUNCTION(frankenphp_handle_request) {
 #endif
 
   frankenphp_worker_request_shutdown();
+  frankenphp_report_included_files();
   go_frankenphp_finish_worker_request(thread_index, callback_ret);
   if (result.r1 != NULL) {
     zval_ptr_dtor(result.r1);
@@ -1017,6 +1184,353 @@ PHP_FUNCTION(frankenphp_log) {
   }
 }
 
+/* {{{ runs the callback inside a child span of the current request span */
+PHP_FUNCTION(frankenphp_trace_span) {
+  zend_string *name;
+  zend_fcall_info fci;
+  zend_fcall_info_cache fcc;
+
+  ZEND_PARSE_PARAMETERS_START(2, 2)
+  Z_PARAM_STR(name)
+  Z_PARAM_FUNC(fci, fcc)
+  ZEND_PARSE_PARAMETERS_END();
+
+  uintptr_t idx = frankenphp_thread_index();
+  go_frankenphp_start_span(idx, ZSTR_VAL(name), ZSTR_LEN(name));
+
+  fci.size = sizeof fci;
+  fci.retval = return_value;
+  fci.params = NULL;
+  fci.param_count = 0;
+
+  zend_call_function(&fci, &fcc);
+
+  if (EG(exception) == NULL) {
+    go_frankenphp_end_span(idx, NULL, 0);
+    return;
+  }
+
+  /* exit() unwinds the stack using an internal object that has no message,
+   * end the span and let it propagate */
+  if (zend_is_unwind_exit(EG(exception)) ||
+      zend_is_graceful_exit(EG(exception))) {
+    go_frankenphp_end_span(idx, NULL, 0);
+    return;
+  }
+
+  /* mark the span as failed with the message of the uncaught exception */
+  zval rv;
+  zval *message = zend_read_property_ex(
+      zend_get_exception_base(EG(exception)), EG(exception),
+      ZSTR_KNOWN(ZEND_STR_MESSAGE), 1, &rv);
+  zend_string *class_name = EG(exception)->ce->name;
+  if (message != NULL && Z_TYPE_P(message) == IS_STRING &&
+      Z_STRLEN_P(message) > 0) {
+    go_frankenphp_end_span(idx, Z_STRVAL_P(message), Z_STRLEN_P(message));
+  } else {
+    go_frankenphp_end_span(idx, ZSTR_VAL(class_name), ZSTR_LEN(class_name));
+  }
+} /* }}} */
+
+PHP_FUNCTION(frankenphp_send_message) {
+  zend_string *worker;
+  zval *payload;
+
+  ZEND_PARSE_PARAMETERS_START(2, 2)
+  Z_PARAM_STR(worker)
+  Z_PARAM_ZVAL(payload)
+  ZEND_PARSE_PARAMETERS_END();
+
+  struct go_frankenphp_send_message_return result =
+      go_frankenphp_send_message(frankenphp_thread_index(), worker, payload);
+  if (result.r1 != NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, result.r1, 0);
+    free(result.r1);
+    RETURN_THROWS();
+  }
+
+  RETVAL_COPY_VALUE(result.r0);
+  efree(result.r0);
+}
+
+PHP_FUNCTION(frankenphp_publish) {
+  zend_string *topic;
+  zval *payload;
+
+  ZEND_PARSE_PARAMETERS_START(2, 2)
+  Z_PARAM_STR(topic)
+  Z_PARAM_ZVAL(payload)
+  ZEND_PARSE_PARAMETERS_END();
+
+  struct go_frankenphp_publish_return result =
+      go_frankenphp_publish(topic, payload);
+  if (result.r1 != NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, result.r1, 0);
+    free(result.r1);
+    RETURN_THROWS();
+  }
+
+  RETURN_LONG(result.r0);
+}
+
+PHP_FUNCTION(frankenphp_should_stop) {
+  ZEND_PARSE_PARAMETERS_NONE();
+
+  RETURN_BOOL(go_frankenphp_should_stop(thread_index));
+}
+
+PHP_FUNCTION(frankenphp_cache_get) {
+  zend_string *key;
+  zval *default_value = NULL;
+
+  ZEND_PARSE_PARAMETERS_START(1, 2)
+  Z_PARAM_STR(key)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_ZVAL(default_value)
+  ZEND_PARSE_PARAMETERS_END();
+
+  struct go_frankenphp_cache_get_return result = go_frankenphp_cache_get(key);
+  if (!result.r1) {
+    if (default_value != NULL) {
+      RETURN_COPY(default_value);
+    }
+
+    RETURN_NULL();
+  }
+
+  RETVAL_COPY_VALUE(result.r0);
+  efree(result.r0);
+}
+
+PHP_FUNCTION(frankenphp_cache_set) {
+  zend_string *key;
+  zval *value;
+  zend_long ttl = 0;
+
+  ZEND_PARSE_PARAMETERS_START(2, 3)
+  Z_PARAM_STR(key)
+  Z_PARAM_ZVAL(value)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_LONG(ttl)
+  ZEND_PARSE_PARAMETERS_END();
+
+  struct go_frankenphp_cache_set_return result =
+      go_frankenphp_cache_set(key, value, ttl);
+  if (result.r1 != NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, result.r1, 0);
+    free(result.r1);
+    RETURN_THROWS();
+  }
+
+  RETURN_BOOL(result.r0);
+}
+
+PHP_FUNCTION(frankenphp_cache_delete) {
+  zend_string *key;
+
+  ZEND_PARSE_PARAMETERS_START(1, 1)
+  Z_PARAM_STR(key)
+  ZEND_PARSE_PARAMETERS_END();
+
+  RETURN_BOOL(go_frankenphp_cache_delete(key));
+}
+
+PHP_FUNCTION(frankenphp_cache_increment) {
+  zend_string *key;
+  zend_long step = 1;
+  zend_long ttl = 0;
+
+  ZEND_PARSE_PARAMETERS_START(1, 3)
+  Z_PARAM_STR(key)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_LONG(step)
+  Z_PARAM_LONG(ttl)
+  ZEND_PARSE_PARAMETERS_END();
+
+  struct go_frankenphp_cache_increment_return result =
+      go_frankenphp_cache_increment(key, step, ttl);
+  if (result.r1 != NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, result.r1, 0);
+    free(result.r1);
+    RETURN_THROWS();
+  }
+
+  RETURN_LONG(result.r0);
+}
+
+PHP_FUNCTION(frankenphp_send_file) {
+  zend_string *path;
+  HashTable *headers = NULL;
+  zend_long offset = 0;
+  bool offset_is_null = true;
+  zend_long length = 0;
+  bool length_is_null = true;
+
+  ZEND_PARSE_PARAMETERS_START(1, 4)
+  Z_PARAM_PATH_STR(path)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_ARRAY_HT(headers)
+  Z_PARAM_LONG_OR_NULL(offset, offset_is_null)
+  Z_PARAM_LONG_OR_NULL(length, length_is_null)
+  ZEND_PARSE_PARAMETERS_END();
+
+  if (!offset_is_null && offset < 0) {
+    zend_argument_value_error(3, "must be greater than or equal to 0");
+    RETURN_THROWS();
+  }
+
+  if (!length_is_null && length < 0) {
+    zend_argument_value_error(4, "must be greater than or equal to 0");
+    RETURN_THROWS();
+  }
+
+  zend_string *name;
+  if (headers != NULL) {
+    ZEND_HASH_FOREACH_STR_KEY(headers, name) {
+      if (name == NULL) {
+        zend_argument_value_error(
+            2, "must be an array of header names and values");
+        RETURN_THROWS();
+      }
+    }
+    ZEND_HASH_FOREACH_END();
+  }
+
+  if (SG(headers_sent)) {
+    zend_throw_exception(spl_ce_RuntimeException,
+                         "the headers have already been sent", 0);
+    RETURN_THROWS();
+  }
+
+  char resolved_path[MAXPATHLEN];
+  if (expand_filepath(ZSTR_VAL(path), resolved_path) == NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, "invalid path", 0);
+    RETURN_THROWS();
+  }
+
+  if (php_check_open_basedir(resolved_path)) {
+    zend_throw_exception(spl_ce_RuntimeException,
+                         "the file is not within the allowed path(s)", 0);
+    RETURN_THROWS();
+  }
+
+  char *error = go_frankenphp_send_file(frankenphp_thread_index(),
+                                        resolved_path, offset,
+                                        length_is_null ? -1 : length);
+  if (error != NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, error, 0);
+    free(error);
+    RETURN_THROWS();
+  }
+
+  if (headers == NULL) {
+    return;
+  }
+
+  zval *value;
+  ZEND_HASH_FOREACH_STR_KEY_VAL(headers, name, value) {
+    zend_string *v = zval_get_string(value);
+    sapi_header_line ctr = {0};
+    ctr.line_len =
+        spprintf(&ctr.line, 0, "%s: %s", ZSTR_VAL(name), ZSTR_VAL(v));
+    sapi_header_op(SAPI_HEADER_REPLACE, &ctr);
+    efree(ctr.line);
+    zend_string_release(v);
+  }
+  ZEND_HASH_FOREACH_END();
+}
+
+PHP_FUNCTION(frankenphp_ws_send) {
+  zend_string *connection_id;
+  zend_string *data;
+
+  ZEND_PARSE_PARAMETERS_START(2, 2)
+  Z_PARAM_STR(connection_id)
+  Z_PARAM_STR(data)
+  ZEND_PARSE_PARAMETERS_END();
+
+  char *error = go_frankenphp_ws_send(connection_id, data);
+  if (error != NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, error, 0);
+    free(error);
+    RETURN_THROWS();
+  }
+}
+
+PHP_FUNCTION(frankenphp_ws_close) {
+  zend_string *connection_id;
+
+  ZEND_PARSE_PARAMETERS_START(1, 1)
+  Z_PARAM_STR(connection_id)
+  ZEND_PARSE_PARAMETERS_END();
+
+  RETURN_BOOL(go_frankenphp_ws_close(connection_id));
+}
+
+PHP_FUNCTION(frankenphp_sse_subscribe) {
+  zend_string *channel;
+
+  ZEND_PARSE_PARAMETERS_START(1, 1)
+  Z_PARAM_STR(channel)
+  ZEND_PARSE_PARAMETERS_END();
+
+  if (SG(headers_sent)) {
+    zend_throw_exception(spl_ce_RuntimeException,
+                         "the headers have already been sent", 0);
+    RETURN_THROWS();
+  }
+
+  uintptr_t idx = frankenphp_thread_index();
+  char *error = go_frankenphp_sse_subscribe(idx, channel);
+  if (error != NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, error, 0);
+    free(error);
+    RETURN_THROWS();
+  }
+
+  static const char *headers[] = {"Content-Type: text/event-stream",
+                                  "Cache-Control: no-cache",
+                                  "X-Accel-Buffering: no"};
+  for (size_t i = 0; i < sizeof(headers) / sizeof(headers[0]); i++) {
+    sapi_header_line ctr = {0};
+    ctr.line = (char *)headers[i];
+    ctr.line_len = strlen(headers[i]);
+    sapi_header_op(SAPI_HEADER_REPLACE, &ctr);
+  }
+
+  /* the events are streamed by Go once the thread has been released */
+  php_output_end_all();
+  php_header();
+
+  go_frankenphp_finish_php_request(idx);
+}
+
+PHP_FUNCTION(frankenphp_sse_publish) {
+  zend_string *channel;
+  zend_string *event;
+  zend_string *data;
+  zend_string *id = NULL;
+
+  ZEND_PARSE_PARAMETERS_START(3, 4)
+  Z_PARAM_STR(channel)
+  Z_PARAM_STR(event)
+  Z_PARAM_STR(data)
+  Z_PARAM_OPTIONAL
+  Z_PARAM_STR_OR_NULL(id)
+  ZEND_PARSE_PARAMETERS_END();
+
+  struct go_frankenphp_sse_publish_return result =
+      go_frankenphp_sse_publish(channel, event, data, id);
+  if (result.r1 != NULL) {
+    zend_throw_exception(spl_ce_RuntimeException, result.r1, 0);
+    free(result.r1);
+    RETURN_THROWS();
+  }
+
+  RETVAL_STRING(result.r0);
+  free(result.r0);
+}
+
 /* {{{ thread-safe opcache reset */
 PHP_FUNCTION(frankenphp_opcache_reset) {
   go_schedule_opcache_reset(frankenphp_thread_index());
@@ -1081,6 +1595,12 @@ PHP_MINIT_FUNCTION(frankenphp) {
   pthread_once(&atfork_once, frankenphp_register_atfork);
 #endif
 
+  /* MINIT runs again after a reboot, do not chain to ourselves */
+  if (zend_interrupt_function != frankenphp_interrupt_function) {
+    original_interrupt_function = zend_interrupt_function;
+    zend_interrupt_function = frankenphp_interrupt_function;
+  }
+
 #ifdef FRANKENPHP_TEST
   if (zend_register_functions(NULL, frankenphp_test_hook_functions, NULL,
                               MODULE_PERSISTENT) == FAILURE) {
@@ -1520,12 +2040,29 @@ static void *php_thread(void *arg) {
         zend_bailout();
       }
 
+#ifdef ZEND_MAX_EXECUTION_TIMERS
+      /* Background workers run their own loop, max_execution_time doesn't
+       * apply unless the script calls set_time_limit() */
+      if (is_background_worker_thread) {
+        zend_unset_timeout();
+      }
+#endif
+
 #if PHP_VERSION_ID < 80500
       /* Override opcache here again if loaded as a shared extension
        * (php 8.4 and under) */
       frankenphp_override_opcache_reset();
 #endif
 
+      /* A restarted worker must not execute the scripts cached before the
+       * restart */
+      if (__atomic_exchange_n(
+              &thread_metrics[thread_index].opcache_invalidation_requested,
+              false, __ATOMIC_RELAXED)) {
+        go_frankenphp_invalidate_opcache(thread_index);
+      }
+      reported_included_files = 0;
+
       zend_file_handle file_handle;
       zend_stream_init_filename(&file_handle, scriptName);
 
@@ -1545,6 +2082,8 @@ static void *php_thread(void *arg) {
       /* Update the last memory usage for metrics */
       __atomic_store_n(&thread_metrics[thread_index].last_memory_usage,
                        zend_memory_usage(0), __ATOMIC_RELAXED);
+      __atomic_store_n(&thread_metrics[thread_index].last_peak_memory_usage,
+                       zend_memory_peak_usage(0), __ATOMIC_RELAXED);
 
       has_attempted_shutdown = true;
 
@@ -1711,6 +2250,7 @@ static void *php_main(void *arg) {
   cfg_get_string("filter.default", &default_filter);
   should_filter_var = default_filter != NULL;
   original_user_abort_setting = PG(ignore_user_abort);
+  original_register_argc_argv = PG(register_argc_argv);
 
   go_frankenphp_main_thread_is_ready();
 
@@ -1814,6 +2354,11 @@ size_t frankenphp_get_thread_memory_usage(uintptr_t thread_index) {
                          __ATOMIC_RELAXED);
 }
 
+size_t frankenphp_get_thread_peak_memory_usage(uintptr_t thread_index) {
+  return __atomic_load_n(&thread_metrics[thread_index].last_peak_memory_usage,
+                         __ATOMIC_RELAXED);
+}
+
 static zend_module_entry **modules = NULL;
 static int modules_len = 0;
 static int (*original_php_register_internal_extensions_func)(void) = NULL;
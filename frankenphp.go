@@ -30,12 +30,15 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 	"unsafe"
+
+	"github.com/dunglas/frankenphp/internal/cpu"
 	// debug on Linux
 	//_ "github.com/ianlancetaylor/cgosymbolizer"
 )
@@ -45,14 +48,18 @@ type contextKeyStruct struct{}
 var (
 	ErrInvalidRequest     = errors.New("not a FrankenPHP request")
 	ErrAlreadyStarted     = errors.New("FrankenPHP is already started")
+	ErrNotStarted         = errors.New("FrankenPHP is not started")
 	ErrInvalidPHPVersion  = errors.New("FrankenPHP is only compatible with PHP 8.2+")
 	ErrMainThreadCreation = errors.New("error creating the main thread")
 	ErrScriptExecution    = errors.New("error during PHP script execution")
+	ErrWorkerNotFound     = errors.New("worker not found")
 	ErrNotRunning         = errors.New("server is not registered, you must first call frankenphp.Init() with the WithServer() option")
 
-	ErrInvalidRequestPath         = ErrRejected{"invalid request path", http.StatusBadRequest}
-	ErrInvalidContentLengthHeader = ErrRejected{"invalid Content-Length header", http.StatusBadRequest}
-	ErrMaxWaitTimeExceeded        = ErrRejected{"maximum request handling time exceeded", http.StatusServiceUnavailable}
+	ErrInvalidRequestPath         = ErrRejected{message: "invalid request path", status: http.StatusBadRequest}
+	ErrInvalidContentLengthHeader = ErrRejected{message: "invalid Content-Length header", status: http.StatusBadRequest}
+	ErrMaxWaitTimeExceeded        = ErrRejected{message: "maximum request handling time exceeded", status: http.StatusServiceUnavailable}
+	// ErrQueueFull is returned when a request is shed because the queue is full, see WithQueueFullResponse() to change the status
+	ErrQueueFull = ErrRejected{message: "request queue is full", status: http.StatusServiceUnavailable}
 
 	contextKey   = contextKeyStruct{}
 	serverHeader = []string{"FrankenPHP"}
@@ -69,18 +76,30 @@ var (
 
 	// atomic: read by in-flight requests while a reload may rewrite it
 	maxWaitTime          atomic.Int64
+	maxQueueLength       atomic.Int32
 	maxRequestsPerThread int
+	maxThreadMemory      int64
+	queueFullErr         = ErrQueueFull
 )
 
 type ErrRejected struct {
 	message string
 	status  int
+	// sent as the Retry-After header if not zero
+	retryAfter time.Duration
 }
 
 func (e ErrRejected) Error() string {
 	return e.message
 }
 
+// Is matches the same rejection even if its status or Retry-After header were customized, e.g. with WithQueueFullResponse()
+func (e ErrRejected) Is(target error) bool {
+	t, ok := target.(ErrRejected)
+
+	return ok && t.message == e.message
+}
+
 type syslogLevel int
 
 const (
@@ -157,15 +176,23 @@ func Config() PHPConfig {
 }
 
 func calculateMaxThreads(opt *opt) (numWorkers int, _ error) {
-	maxProcs := runtime.GOMAXPROCS(0) * 2
+	maxProcs := cpu.NumCPUs() * 2
 	maxThreadsFromWorkers := 0
 
 	for i, w := range opt.workers {
-		if w.num <= 0 {
+		if w.num <= 0 && w.background {
+			// a single consumer or scheduler is the safe default
+			opt.workers[i].num = 1
+		} else if w.num <= 0 {
 			// https://github.com/php/frankenphp/issues/126
 			opt.workers[i].num = maxProcs
 		}
 		metrics.TotalWorkers(w.name, w.num)
+		if w.server != nil {
+			metrics.WorkerServer(w.name, w.server.name)
+		} else {
+			metrics.WorkerServer(w.name, "")
+		}
 
 		numWorkers += opt.workers[i].num
 
@@ -182,6 +209,11 @@ func calculateMaxThreads(opt *opt) (numWorkers int, _ error) {
 		}
 	}
 
+	// threads dedicated to a server are reserved like worker threads
+	for _, s := range opt.servers {
+		numWorkers += s.minThreads
+	}
+
 	numThreadsIsSet := opt.numThreads > 0
 	maxThreadsIsSet := opt.maxThreads != 0
 	maxThreadsIsAuto := opt.maxThreads < 0 // maxthreads < 0 signifies auto mode (see phpmaintread.go)
@@ -199,7 +231,7 @@ func calculateMaxThreads(opt *opt) (numWorkers int, _ error) {
 	if numThreadsIsSet && !maxThreadsIsSet {
 		opt.maxThreads = opt.numThreads
 		if opt.numThreads <= numWorkers {
-			return 0, fmt.Errorf("num_threads (%d) must be greater than the number of worker threads (%d)", opt.numThreads, numWorkers)
+			return 0, fmt.Errorf("num_threads (%d) must be greater than the number of worker and server threads (%d)", opt.numThreads, numWorkers)
 		}
 
 		return numWorkers, nil
@@ -208,7 +240,7 @@ func calculateMaxThreads(opt *opt) (numWorkers int, _ error) {
 	if maxThreadsIsSet && !numThreadsIsSet {
 		opt.numThreads = numWorkers + 1
 		if !maxThreadsIsAuto && opt.numThreads > opt.maxThreads {
-			return 0, fmt.Errorf("max_threads (%d) must be greater than the number of worker threads (%d)", opt.maxThreads, numWorkers)
+			return 0, fmt.Errorf("max_threads (%d) must be greater than the number of worker and server threads (%d)", opt.maxThreads, numWorkers)
 		}
 
 		return numWorkers, nil
@@ -228,7 +260,7 @@ func calculateMaxThreads(opt *opt) (numWorkers int, _ error) {
 
 	// both num_threads and max_threads are set
 	if opt.numThreads <= numWorkers {
-		return 0, fmt.Errorf("num_threads (%d) must be greater than the number of worker threads (%d)", opt.numThreads, numWorkers)
+		return 0, fmt.Errorf("num_threads (%d) must be greater than the number of worker and server threads (%d)", opt.numThreads, numWorkers)
 	}
 
 	if !maxThreadsIsAuto && opt.maxThreads < opt.numThreads {
@@ -275,13 +307,45 @@ func Init(options ...Option) error {
 		metrics = opt.metrics
 	}
 
+	if opt.tracer != nil {
+		tracer = opt.tracer.Tracer(tracerName)
+	}
+
 	maxWaitTime.Store(int64(opt.maxWaitTime))
+	maxQueueLength.Store(int32(opt.maxQueueLength))
 	maxRequestsPerThread = opt.maxRequests
+	maxThreadMemory = opt.maxThreadMemory
+	slowlogTimeout = opt.slowlogTimeout
+
+	cacheMaxSize := opt.cacheMaxSize
+	if cacheMaxSize == 0 {
+		cacheMaxSize = defaultCacheMaxSize
+	}
+	sharedCache = newCache(cacheMaxSize)
+
+	sseHistorySize := opt.sseHistorySize
+	if sseHistorySize == 0 {
+		sseHistorySize = defaultSSEHistorySize
+	}
+	if sseHub == nil {
+		sseHub = newSSEBroker(sseHistorySize)
+	} else {
+		sseHub.reopen(sseHistorySize)
+	}
+
+	if opt.queueFullStatus != 0 {
+		queueFullErr.status = opt.queueFullStatus
+	}
+	queueFullErr.retryAfter = opt.queueFullRetryAfter
 
 	if opt.maxIdleTime > 0 {
 		maxIdleTime = opt.maxIdleTime
 	}
 
+	if opt.scalingPolicy != nil {
+		scalingPolicy = opt.scalingPolicy
+	}
+
 	registerServers(opt.servers)
 
 	workerThreadCount, err := calculateMaxThreads(opt)
@@ -324,6 +388,13 @@ func Init(options ...Option) error {
 		convertToRegularThread(getInactivePHPThread())
 	}
 
+	for _, s := range servers {
+		for i := 0; i < s.minThreads; i++ {
+			convertToServerThread(getInactivePHPThread(), s)
+		}
+		metrics.TotalServerThreads(s.name, s.minThreads)
+	}
+
 	if err := initWorkers(opt.workers); err != nil {
 		shutdown()
 
@@ -336,10 +407,13 @@ func Init(options ...Option) error {
 	}
 
 	initAutoScaling(mainThread)
+	initSlowlog(mainThread)
 
 	// only now that the workers and threads are up may requests reach a server
 	activateServers()
 
+	initScheduler(opt.scheduledTasks)
+
 	if globalLogger.Enabled(globalCtx, slog.LevelInfo) {
 		globalLogger.LogAttrs(globalCtx, slog.LevelInfo, "FrankenPHP started 🐘", slog.String("php_version", Version().Version), slog.Int("num_threads", mainThread.numThreads), slog.Int("max_threads", mainThread.maxThreads), slog.Int("max_requests", maxRequestsPerThread))
 
@@ -381,6 +455,9 @@ func shutdown() {
 	}
 
 	drainWatchers()
+	drainScheduler()
+	drainWebSockets()
+	drainSSE()
 	drainPHPThreads()
 	unregisterServers()
 
@@ -436,6 +513,20 @@ func go_ub_write(threadIndex C.uintptr_t, cBuf *C.char, length C.size_t) (C.size
 		return C.size_t(length), C.bool(fc.clientHadClosed)
 	}
 
+	if fc.outputReplaced() {
+		// the body is replaced by the file sent once the script has finished
+		return C.size_t(length), C.bool(false)
+	}
+
+	if fc.outputBufferSize > 0 && fc.responseWriter != nil {
+		err := fc.outputBuffer().write(unsafe.Slice((*byte)(unsafe.Pointer(cBuf)), length))
+		if err != nil && fc.logger.Enabled(fc.ctx, slog.LevelDebug) {
+			fc.logger.LogAttrs(fc.ctx, slog.LevelDebug, "write error", slog.Any("error", err))
+		}
+
+		return C.size_t(length), C.bool(err != nil || fc.clientHasClosed())
+	}
+
 	var writer io.Writer
 	if fc.responseWriter == nil {
 		var b bytes.Buffer
@@ -467,7 +558,11 @@ func go_apache_request_headers(threadIndex C.uintptr_t) (*C.go_string, C.size_t)
 		// worker mode, not handling a request
 
 		if fc.logger.Enabled(fc.ctx, slog.LevelDebug) {
-			fc.logger.LogAttrs(fc.ctx, slog.LevelDebug, "apache_request_headers() called in non-HTTP context", slog.String("worker", fc.worker.name))
+			attrs := []slog.Attr{}
+			if fc.worker != nil {
+				attrs = append(attrs, slog.String("worker", fc.worker.name))
+			}
+			fc.logger.LogAttrs(fc.ctx, slog.LevelDebug, "apache_request_headers() called in non-HTTP context", attrs...)
 		}
 
 		return nil, 0
@@ -577,8 +672,36 @@ func go_write_headers(threadIndex C.uintptr_t, status C.int, headers *C.zend_lli
 		goStatus = 500
 	}
 
+	// the execution has been interrupted by the request timeout
+	if fc.timedOut.Load() {
+		goStatus = http.StatusGatewayTimeout
+	}
+
+	// the file will be sent by serveSendfile(), the output of the script is discarded
+	if fc.sendfile != nil && goStatus >= 200 && !fc.timedOut.Load() && fc.detectSendfile() {
+		fc.status = goStatus
+
+		return C.bool(true)
+	}
+
+	// the file passed to frankenphp_send_file() is only sent with successful responses,
+	// the output of the script is sent instead
+	if fc.sentFile != nil && goStatus >= 300 {
+		_ = fc.sentFile.file.Close()
+		fc.sentFile = nil
+	}
+
+	// the length of the response is known, this allows using sendfile(2)
+	if fc.sentFile != nil && goStatus >= 200 {
+		fc.responseWriter.Header().Set("Content-Length", strconv.FormatInt(fc.sentFile.length, 10))
+	}
+
 	fc.responseWriter.WriteHeader(goStatus)
 
+	if goStatus >= 200 {
+		fc.status = goStatus
+	}
+
 	if goStatus < 200 {
 		// Clear headers, it's not automatically done by ResponseWriter.WriteHeader() for 1xx responses
 		h := fc.responseWriter.Header()
@@ -606,6 +729,20 @@ func go_sapi_flush(threadIndex C.uintptr_t) bool {
 		return true
 	}
 
+	// flushing would send the headers before the file
+	if fc.outputReplaced() {
+		return false
+	}
+
+	if fc.outputBufferSize > 0 {
+		// the headers must be written by the PHP thread before the drain goroutine starts
+		if fc.status != 0 {
+			fc.outputBuffer().requestFlush()
+		}
+
+		return false
+	}
+
 	if fc.responseController == nil {
 		fc.responseController = http.NewResponseController(fc.responseWriter)
 	}
@@ -812,5 +949,13 @@ func resetGlobals() {
 	servers = nil
 	watcherIsEnabled = false
 	maxIdleTime = defaultMaxIdleTime
+	scalingPolicy = NewCPUScalingPolicy()
+	regularWaitTimes = &waitTimeRecorder{}
 	maxRequestsPerThread = 0
+	maxThreadMemory = 0
+	slowlogTimeout = 0
+	sharedCache = nil
+	tracer = nil
+	queueFullErr = ErrQueueFull
+	wsDraining.Store(false)
 }
@@ -164,7 +164,8 @@ int frankenphp_new_main_thread(int num_threads);
 bool frankenphp_new_php_thread(uintptr_t thread_index);
 
 bool frankenphp_shutdown_dummy_request(void);
-void frankenphp_update_local_thread_context(bool is_worker);
+void frankenphp_update_local_thread_context(bool is_worker,
+                                           bool is_background_worker);
 
 int frankenphp_execute_script_cli(char *script, int argc, char **argv,
                                   bool eval);
@@ -184,11 +185,15 @@ int frankenphp_get_current_memory_limit();
 
 typedef struct {
   size_t last_memory_usage;
+  size_t last_peak_memory_usage;
+  bool backtrace_requested;
+  bool opcache_invalidation_requested;
 } frankenphp_thread_metrics;
 
 void frankenphp_init_thread_metrics(int max_threads);
 void frankenphp_destroy_thread_metrics(void);
 size_t frankenphp_get_thread_memory_usage(uintptr_t thread_index);
+size_t frankenphp_get_thread_peak_memory_usage(uintptr_t thread_index);
 
 /* Best-effort force-kill primitives. The slot is populated by each PHP
  * thread at boot (an internal helper calls back into Go via
@@ -201,6 +206,39 @@ size_t frankenphp_get_thread_memory_usage(uintptr_t thread_index);
 void frankenphp_force_kill_thread(force_kill_slot slot);
 void frankenphp_release_thread_for_kill(force_kill_slot slot);
 
+/* A single frame of a PHP call stack, the strings are only valid during the
+ * go_frankenphp_report_backtrace call */
+typedef struct {
+  const char *class_name;
+  size_t class_name_len;
+  const char *function;
+  size_t function_len;
+  const char *file;
+  size_t file_len;
+  uint32_t line;
+} frankenphp_stack_frame;
+
+/* Asks the thread to report its call stack to Go at the next VM interrupt.
+ * Unlike force_kill_thread, blocking syscalls are not woken up. */
+void frankenphp_request_backtrace(force_kill_slot slot, uintptr_t idx);
+
+/* Resets the flags set by force_kill_thread on the current thread, a pending
+ * backtrace request is kept. */
+void frankenphp_clear_kill_signal(void);
+
+/* Asks the thread to call go_frankenphp_invalidate_opcache before executing
+ * its next script. */
+void frankenphp_request_opcache_invalidation(uintptr_t idx);
+
+/* Removes a script from the opcache like opcache_invalidate($file, true).
+ * Unlike opcache_reset(), this is safe while other threads are running. Must
+ * be called from a PHP thread during a request. */
+void frankenphp_invalidate_script(const char *file, size_t len);
+
+/* Invalidates every script cached in the opcache, see
+ * frankenphp_invalidate_script(). */
+void frankenphp_invalidate_all_scripts(void);
+
 void register_extensions(zend_module_entry **m, int len);
 
 #endif
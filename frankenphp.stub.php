@@ -54,3 +54,71 @@ function mercure_publish(string|array $topics, string $data = '', bool $private
  * array<string, any> $context Values of the array will be converted to the corresponding Go type (if supported by FrankenPHP) and added to the context of the structured logs using https://pkg.go.dev/log/slog#Attr
  */
 function frankenphp_log(string $message, int $level = 0, array $context = []): void {}
+
+/**
+ * Runs the callback inside a new OpenTelemetry span, child of the span of the current request, and returns its return value.
+ */
+function frankenphp_trace_span(string $name, callable $callback): mixed {}
+
+/**
+ * Passes the payload to the callback of frankenphp_handle_request() of a thread of the worker, and returns the value returned by the callback.
+ */
+function frankenphp_send_message(string $worker, mixed $payload): mixed {}
+
+/**
+ * Passes the payload to the callback of frankenphp_handle_request() of every worker subscribed to the topic without waiting for them, and returns the number of workers.
+ */
+function frankenphp_publish(string $topic, mixed $payload): int {}
+
+/**
+ * Returns true when the thread is asked to stop (shutdown, restart or file change), background workers must then return.
+ */
+function frankenphp_should_stop(): bool {}
+
+/**
+ * Returns the value stored in the cache shared by all PHP threads, or $default if there is none or it has expired.
+ */
+function frankenphp_cache_get(string $key, mixed $default = null): mixed {}
+
+/**
+ * Stores the value in the cache shared by all PHP threads for $ttl seconds (0 = until evicted), returns false if the value is larger than the cache.
+ */
+function frankenphp_cache_set(string $key, mixed $value, int $ttl = 0): bool {}
+
+/**
+ * Removes the value from the cache shared by all PHP threads, returns false if there was none.
+ */
+function frankenphp_cache_delete(string $key): bool {}
+
+/**
+ * Atomically adds $step to the integer stored in the cache shared by all PHP threads and returns the new value.
+ * The entry is created with the value $step and the given TTL if it doesn't exist.
+ */
+function frankenphp_cache_increment(string $key, int $step = 1, int $ttl = 0): int {}
+
+/**
+ * Sends the file in place of the output of the script, once the script has finished and the PHP thread has been released.
+ * $headers are added to the response, $offset and $length allow sending only a part of the file.
+ */
+function frankenphp_send_file(string $path, array $headers = [], ?int $offset = null, ?int $length = null): void {}
+
+/**
+ * Sends a message to the WebSocket connection, as a text frame if it is valid UTF-8 or as a binary frame otherwise.
+ */
+function frankenphp_ws_send(string $connectionId, string $data): void {}
+
+/**
+ * Closes the WebSocket connection, the worker then receives its close event. Returns false if the connection doesn't exist.
+ */
+function frankenphp_ws_close(string $connectionId): bool {}
+
+/**
+ * Finishes the request and streams the events published to the channel with frankenphp_sse_publish() to the client,
+ * without occupying a PHP thread. The events published after the one in the Last-Event-ID request header are replayed.
+ */
+function frankenphp_sse_subscribe(string $channel): void {}
+
+/**
+ * Sends a Server-Sent Event to all the clients subscribed to the channel and returns its ID, generated if null.
+ */
+function frankenphp_sse_publish(string $channel, string $event, string $data, ?string $id = null): string {}
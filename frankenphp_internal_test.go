@@ -0,0 +1,20 @@
+package frankenphp
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrRejectedIsWithCustomStatus(t *testing.T) {
+	err := ErrQueueFull
+	err.status = http.StatusTooManyRequests
+	err.retryAfter = time.Second
+
+	assert.ErrorIs(t, err, ErrQueueFull)
+	assert.ErrorIs(t, fmt.Errorf("request failed: %w", err), ErrQueueFull)
+	assert.NotErrorIs(t, err, ErrMaxWaitTimeExceeded)
+}
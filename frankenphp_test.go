@@ -33,6 +33,8 @@ import (
 
 	"github.com/dunglas/frankenphp"
 	"github.com/dunglas/frankenphp/internal/fastabs"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -199,6 +201,122 @@ func testFinishRequest(t *testing.T, opts *testOptions) {
 	}, opts)
 }
 
+func TestRequestTimeout_module(t *testing.T) { testRequestTimeout(t, &testOptions{}) }
+func TestRequestTimeout_worker(t *testing.T) {
+	testRequestTimeout(t, &testOptions{workerScript: "sleep.php"})
+}
+func testRequestTimeout(t *testing.T, opts *testOptions) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "freebsd" && runtime.GOOS != "windows" {
+		t.Skipf("force-kill cannot interrupt blocking syscalls on %s", runtime.GOOS)
+	}
+
+	opts.nbParallelRequests = 1
+	opts.requestOpts = append(opts.requestOpts, frankenphp.WithRequestTimeout(200*time.Millisecond))
+
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, _ int) {
+		start := time.Now()
+		body, resp := testGet("http://example.com/sleep.php?sleep=10000", handler, t)
+
+		assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+		assert.NotContains(t, body, "slept for")
+		assert.Less(t, time.Since(start), 5*time.Second)
+
+		// the thread must be usable again
+		body, resp = testGet("http://example.com/sleep.php?sleep=1", handler, t)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, body, "slept for 1 ms")
+	}, opts)
+}
+
+func TestRequestTimeoutRestartsWorker(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "freebsd" && runtime.GOOS != "windows" {
+		t.Skipf("force-kill cannot interrupt blocking syscalls on %s", runtime.GOOS)
+	}
+
+	registry := prometheus.NewRegistry()
+
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, _ int) {
+		_, resp := testGet("http://example.com/sleep.php?sleep=10000", handler, t)
+		assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+
+		// requests finishing within the timeout right after must not be interrupted
+		for range 20 {
+			body, resp := testGet("http://example.com/sleep.php?sleep=1", handler, t)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Contains(t, body, "slept for 1 ms")
+		}
+
+		count, err := testutil.GatherAndCount(registry, "frankenphp_worker_crashes")
+		require.NoError(t, err)
+		assert.Zero(t, count, "a timeout is not a crash")
+
+		require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+			# HELP frankenphp_worker_timeouts Number of PHP worker restarts caused by a request exceeding its request timeout for this worker
+			# TYPE frankenphp_worker_timeouts counter
+			frankenphp_worker_timeouts{server="",worker="workerName"} 1
+		`), "frankenphp_worker_timeouts"))
+	}, &testOptions{
+		workerScript:       "sleep.php",
+		nbWorkers:          1,
+		nbParallelRequests: 1,
+		initOpts:           []frankenphp.Option{frankenphp.WithMetrics(frankenphp.NewPrometheusMetrics(registry))},
+		requestOpts:        []frankenphp.RequestOption{frankenphp.WithRequestTimeout(200 * time.Millisecond)},
+	})
+}
+
+func TestThreadBacktrace(t *testing.T) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, _ int) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			body, _ := testGet("http://example.com/busy-loop.php?ms=3000", handler, t)
+			assert.Equal(t, "done", body)
+		}()
+
+		var frames []frankenphp.StackFrame
+		require.Eventually(t, func() bool {
+			for _, s := range frankenphp.DebugState().ThreadDebugStates {
+				if s.IsBusy && strings.Contains(s.CurrentURI, "busy-loop.php") {
+					var err error
+					frames, err = frankenphp.ThreadBacktrace(s.Index, time.Second)
+
+					return err == nil
+				}
+			}
+
+			return false
+		}, 2*time.Second, 50*time.Millisecond)
+
+		require.NotEmpty(t, frames)
+		assert.Equal(t, "spin", frames[0].Function)
+		assert.Equal(t, "{main}", frames[len(frames)-1].Function)
+		assert.True(t, strings.HasSuffix(frames[len(frames)-1].File, "busy-loop.php"))
+
+		_, err := frankenphp.ThreadBacktrace(-1, time.Second)
+		assert.ErrorIs(t, err, frankenphp.ErrThreadNotFound)
+
+		<-done
+	}, &testOptions{nbParallelRequests: 1})
+}
+
+func TestSlowlog(t *testing.T) {
+	var buf syncBuffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, _ int) {
+		body, _ := testGet("http://example.com/busy-loop.php?ms=1500", handler, t)
+		assert.Equal(t, "done", body)
+
+		assert.Equal(t, 1, strings.Count(buf.String(), "slow request"), "a slow request must be logged once")
+		assert.Contains(t, buf.String(), "busy-loop.php")
+		assert.Contains(t, buf.String(), "spin()")
+	}, &testOptions{
+		nbParallelRequests: 1,
+		logger:             logger,
+		initOpts:           []frankenphp.Option{frankenphp.WithSlowlogTimeout(500 * time.Millisecond)},
+	})
+}
+
 func TestServerVariable_module(t *testing.T) {
 	testServerVariable(t, nil)
 }
@@ -630,6 +748,12 @@ func TestFlush_module(t *testing.T) { testFlush(t, &testOptions{}) }
 func TestFlush_worker(t *testing.T) {
 	testFlush(t, &testOptions{workerScript: "flush.php"})
 }
+func TestFlushDetachedOutput_module(t *testing.T) {
+	testFlush(t, &testOptions{requestOpts: []frankenphp.RequestOption{frankenphp.WithRequestDetachedOutput(0)}})
+}
+func TestFlushDetachedOutput_worker(t *testing.T) {
+	testFlush(t, &testOptions{workerScript: "flush.php", requestOpts: []frankenphp.RequestOption{frankenphp.WithRequestDetachedOutput(0)}})
+}
 func testFlush(t *testing.T, opts *testOptions) {
 	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, i int) {
 		var j int
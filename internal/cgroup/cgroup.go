@@ -0,0 +1,154 @@
+// Package cgroup reads the resource limits and usage of the current process from the cgroup v2 hierarchy.
+// Containers (Docker, Kubernetes...) use cgroups to enforce their CPU and memory limits,
+// values reported by the host (number of CPUs, total memory) are meaningless inside of them.
+package cgroup
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	mountPoint     = "/sys/fs/cgroup"
+	procSelfCgroup = "/proc/self/cgroup"
+)
+
+// Cgroup is the cgroup v2 of a process
+type Cgroup struct {
+	root string
+	dir  string
+}
+
+// Current returns the cgroup v2 of the current process, or nil if cgroup v2 is not available
+func Current() *Cgroup {
+	return open(mountPoint, procSelfCgroup)
+}
+
+// open resolves the cgroup of a process from the unified hierarchy mounted at root
+// and from the content of /proc/<pid>/cgroup
+func open(root, procCgroup string) *Cgroup {
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		return nil
+	}
+
+	cg := &Cgroup{root: root, dir: root}
+
+	content, err := os.ReadFile(procCgroup)
+	if err != nil {
+		return cg
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		// cgroup v2 entries have the form "0::/path"
+		path, ok := strings.CutPrefix(scanner.Text(), "0::")
+		if !ok {
+			continue
+		}
+
+		// with a cgroup namespace (the default with Docker and Kubernetes), the path is "/"
+		// or may not be visible from the mount point, the root is then the cgroup of the process
+		dir := filepath.Join(root, path)
+		if _, err := os.Stat(dir); err == nil {
+			cg.dir = dir
+		}
+
+		break
+	}
+
+	return cg
+}
+
+// CPULimit returns the number of CPUs the cgroup may use, the lowest quota of the cgroup and its ancestors applies
+func (cg *Cgroup) CPULimit() (float64, bool) {
+	limit := math.Inf(1)
+	cg.walk(func(dir string) {
+		// format: "$MAX $PERIOD", $MAX is "max" if there is no limit
+		fields := strings.Fields(readFile(filepath.Join(dir, "cpu.max")))
+		if len(fields) != 2 || fields[0] == "max" {
+			return
+		}
+
+		quota, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return
+		}
+
+		period, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || period <= 0 {
+			return
+		}
+
+		limit = min(limit, quota/period)
+	})
+
+	if math.IsInf(limit, 1) {
+		return 0, false
+	}
+
+	return limit, true
+}
+
+// CPUUsage returns the total CPU time consumed by the processes of the cgroup
+func (cg *Cgroup) CPUUsage() (time.Duration, bool) {
+	scanner := bufio.NewScanner(strings.NewReader(readFile(filepath.Join(cg.dir, "cpu.stat"))))
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "usage_usec ")
+		if !ok {
+			continue
+		}
+
+		usec, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+
+		return time.Duration(usec) * time.Microsecond, true
+	}
+
+	return 0, false
+}
+
+// MemoryLimit returns the maximum amount of memory the cgroup may use, the lowest limit of the cgroup and its ancestors applies
+func (cg *Cgroup) MemoryLimit() (uint64, bool) {
+	var limit uint64
+	cg.walk(func(dir string) {
+		// "max" if there is no limit
+		v, err := strconv.ParseUint(readFile(filepath.Join(dir, "memory.max")), 10, 64)
+		if err != nil {
+			return
+		}
+
+		if limit == 0 || v < limit {
+			limit = v
+		}
+	})
+
+	return limit, limit > 0
+}
+
+// walk calls fn for the cgroup and each of its ancestors up to the root of the hierarchy
+func (cg *Cgroup) walk(fn func(dir string)) {
+	for dir := cg.dir; ; dir = filepath.Dir(dir) {
+		fn(dir)
+
+		if dir == cg.root || !strings.HasPrefix(dir, cg.root) {
+			return
+		}
+	}
+}
+
+func readFile(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(content))
+}
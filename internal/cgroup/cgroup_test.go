@@ -0,0 +1,53 @@
+package cgroup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCgroupV1IsIgnored(t *testing.T) {
+	assert.Nil(t, open("testdata/v1", "testdata/v1.cgroup"))
+}
+
+func TestCgroupLimits(t *testing.T) {
+	cg := open("testdata/v2", "testdata/pod.cgroup")
+	require.NotNil(t, cg)
+
+	// the lowest limit of the cgroup and its ancestors applies
+	cpus, ok := cg.CPULimit()
+	assert.True(t, ok)
+	assert.Equal(t, 1.5, cpus)
+
+	mem, ok := cg.MemoryLimit()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1<<30), mem)
+
+	usage, ok := cg.CPUUsage()
+	assert.True(t, ok)
+	assert.Equal(t, 2500*time.Millisecond, usage)
+}
+
+func TestCgroupNamespace(t *testing.T) {
+	cg := open("testdata/v2", "testdata/namespaced.cgroup")
+	require.NotNil(t, cg)
+
+	_, ok := cg.CPULimit()
+	assert.False(t, ok)
+
+	mem, ok := cg.MemoryLimit()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(4<<30), mem)
+
+	usage, ok := cg.CPUUsage()
+	assert.True(t, ok)
+	assert.Equal(t, 9*time.Second, usage)
+}
+
+func TestCgroupFallsBackToRoot(t *testing.T) {
+	cg := open("testdata/v2", "testdata/missing.cgroup")
+	require.NotNil(t, cg)
+	assert.Equal(t, "testdata/v2", cg.dir)
+}
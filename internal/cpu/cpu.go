@@ -0,0 +1,65 @@
+package cpu
+
+import (
+	"math"
+	"runtime"
+	"time"
+
+	"github.com/dunglas/frankenphp/internal/cgroup"
+)
+
+// the cgroup v2 of the process, nil if not running in a cgroup v2 (e.g. not in a container)
+var currentCgroup = cgroup.Current()
+
+// NumCPUs returns the number of CPUs available to the process
+// in a container, this is the CPU quota of the cgroup rounded up instead of the number of CPUs of the host
+func NumCPUs() int {
+	n := runtime.GOMAXPROCS(0)
+	if limit, ok := cgroupCPULimit(); ok {
+		n = min(n, max(int(math.Ceil(limit)), 1))
+	}
+
+	return n
+}
+
+func cgroupCPULimit() (float64, bool) {
+	if currentCgroup == nil {
+		return 0, false
+	}
+
+	return currentCgroup.CPULimit()
+}
+
+func cgroupCPUUsage() (time.Duration, bool) {
+	if currentCgroup == nil {
+		return 0, false
+	}
+
+	return currentCgroup.CPUUsage()
+}
+
+// probeCgroupCPUs probes the CPU usage of the whole cgroup relative to its CPU quota
+// other processes of the container (and the CPU throttling) are taken into account
+func probeCgroupCPUs(usageStart time.Duration, probeTime time.Duration, maxCPUUsage float64, abort <-chan struct{}) bool {
+	start := time.Now()
+
+	select {
+	case <-abort:
+		return false
+	case <-time.After(probeTime):
+	}
+
+	usageEnd, ok := cgroupCPUUsage()
+	if !ok {
+		return true
+	}
+
+	cpus, ok := cgroupCPULimit()
+	if !ok {
+		cpus = float64(runtime.NumCPU())
+	}
+
+	cpuUsage := float64(usageEnd-usageStart) / float64(time.Since(start)) / cpus
+
+	return cpuUsage < maxCPUUsage
+}
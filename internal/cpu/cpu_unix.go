@@ -5,16 +5,18 @@ package cpu
 // #include <time.h>
 import "C"
 import (
-	"runtime"
 	"time"
 )
 
-var cpuCount = runtime.GOMAXPROCS(0)
-
 // ProbeCPUs probes the CPU usage of the process
 // if CPUs are not busy, most threads are likely waiting for I/O, so we should scale
 // if CPUs are already busy we won't gain much by scaling and want to avoid the overhead of doing so
-func ProbeCPUs(probeTime time.Duration, maxCPUUsage float64, abort chan struct{}) bool {
+// in a container, the usage of the cgroup is compared to its CPU quota instead
+func ProbeCPUs(probeTime time.Duration, maxCPUUsage float64, abort <-chan struct{}) bool {
+	if usageStart, ok := cgroupCPUUsage(); ok {
+		return probeCgroupCPUs(usageStart, probeTime, maxCPUUsage, abort)
+	}
+
 	var cpuStart, cpuEnd C.struct_timespec
 
 	// note: clock_gettime is a POSIX function
@@ -31,7 +33,7 @@ func ProbeCPUs(probeTime time.Duration, maxCPUUsage float64, abort chan struct{}
 	C.clock_gettime(C.CLOCK_PROCESS_CPUTIME_ID, &cpuEnd)
 	elapsedTime := float64(time.Since(start).Nanoseconds())
 	elapsedCpuTime := float64(cpuEnd.tv_sec-cpuStart.tv_sec)*1e9 + float64(cpuEnd.tv_nsec-cpuStart.tv_nsec)
-	cpuUsage := elapsedCpuTime / elapsedTime / float64(cpuCount)
+	cpuUsage := elapsedCpuTime / elapsedTime / float64(NumCPUs())
 
 	return cpuUsage < maxCPUUsage
 }
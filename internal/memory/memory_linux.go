@@ -1,7 +1,13 @@
 package memory
 
-import "syscall"
+import (
+	"syscall"
 
+	"github.com/dunglas/frankenphp/internal/cgroup"
+)
+
+// TotalSysMemory returns the memory available to the process
+// in a container, this is the memory limit of the cgroup if it is lower than the memory of the host
 func TotalSysMemory() uint64 {
 	sysInfo := &syscall.Sysinfo_t{}
 	err := syscall.Sysinfo(sysInfo)
@@ -9,5 +15,13 @@ func TotalSysMemory() uint64 {
 		return 0
 	}
 
-	return uint64(sysInfo.Totalram) * uint64(sysInfo.Unit)
+	total := uint64(sysInfo.Totalram) * uint64(sysInfo.Unit)
+
+	if cg := cgroup.Current(); cg != nil {
+		if limit, ok := cg.MemoryLimit(); ok && limit < total {
+			return limit
+		}
+	}
+
+	return total
 }
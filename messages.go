@@ -0,0 +1,114 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+	"unsafe"
+)
+
+const (
+	// messageMaxWaitTime is the time a message may wait for a thread of the worker when max_wait_time is disabled
+	messageMaxWaitTime = 30 * time.Second
+	// maxPendingMessages is the number of published messages a worker may have waiting for a thread,
+	// further messages are dropped
+	maxPendingMessages = 1024
+)
+
+// sendMessage passes the message to the callback of a thread of the worker and waits for its return value
+func sendMessage(fc *frankenPHPContext, name string, message any) (any, error) {
+	w, ok := workersByName[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrWorkerNotFound, name)
+	}
+
+	if w.background {
+		return nil, fmt.Errorf("worker %q is a background worker, it does not handle messages", name)
+	}
+
+	// the sending thread is blocked until the message is handled:
+	// if it belongs to the worker, the worker may have no thread left to handle it
+	for sender := fc; sender != nil; sender = sender.messageSender {
+		if sender.worker == w {
+			return nil, fmt.Errorf("worker %q cannot handle a message it sent itself, directly or through other workers", name)
+		}
+	}
+
+	ctx := globalCtx
+	if fc != nil {
+		ctx = fc.ctx
+	}
+
+	mfc := newContextFromMessage(message, nil, ctx, w)
+	mfc.messageSender = fc
+	mfc.fallbackMaxWaitTime = messageMaxWaitTime
+	if err := w.handleRequest(mfc); err != nil {
+		return nil, err
+	}
+
+	return mfc.handlerReturn, nil
+}
+
+// publish passes the message to a thread of every worker subscribed to the topic without waiting for them,
+// it returns the number of workers the message is sent to
+func publish(topic string, message any) int {
+	n := 0
+	for _, w := range workers {
+		if w.background || !slices.Contains(w.topics, topic) {
+			continue
+		}
+
+		select {
+		case w.pendingMessages <- struct{}{}:
+		default:
+			if globalLogger.Enabled(globalCtx, slog.LevelWarn) {
+				globalLogger.LogAttrs(globalCtx, slog.LevelWarn, "too many pending messages, dropping the published message", slog.String("worker", w.name), slog.String("topic", topic))
+			}
+
+			continue
+		}
+
+		n++
+		go func() {
+			defer func() { <-w.pendingMessages }()
+
+			fc := newContextFromMessage(message, nil, globalCtx, w)
+			fc.fallbackMaxWaitTime = messageMaxWaitTime
+			if err := w.handleRequest(fc); err != nil && globalLogger.Enabled(globalCtx, slog.LevelWarn) {
+				globalLogger.LogAttrs(globalCtx, slog.LevelWarn, "unable to deliver the published message", slog.String("worker", w.name), slog.String("topic", topic), slog.Any("error", err))
+			}
+		}()
+	}
+
+	return n
+}
+
+//export go_frankenphp_send_message
+func go_frankenphp_send_message(threadIndex C.uintptr_t, name *C.zend_string, payload *C.zval) (*C.zval, *C.char) {
+	message, err := goValue[any](payload)
+	if err != nil {
+		// PHP exception message.
+		return nil, C.CString("unable to convert the payload: " + err.Error())
+	}
+
+	r, err := sendMessage(phpThreads[threadIndex].handler.frankenPHPContext(), GoString(unsafe.Pointer(name)), message)
+	if err != nil {
+		return nil, C.CString(err.Error())
+	}
+
+	return phpValue(r), nil
+}
+
+//export go_frankenphp_publish
+func go_frankenphp_publish(topic *C.zend_string, payload *C.zval) (C.zend_long, *C.char) {
+	message, err := goValue[any](payload)
+	if err != nil {
+		// PHP exception message.
+		return 0, C.CString("unable to convert the payload: " + err.Error())
+	}
+
+	return C.zend_long(publish(GoString(unsafe.Pointer(topic)), message)), nil
+}
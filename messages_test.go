@@ -0,0 +1,31 @@
+package frankenphp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dunglas/frankenphp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendMessageAndPublish(t *testing.T) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, _ int) {
+		body, _ := testGet("http://example.com/send-message.php?name=Kevin", handler, t)
+
+		assert.Equal(t, "Hello Kevin\nworker \"messages\" cannot handle a message it sent itself, directly or through other workers\n1\n0\nworker not found: \"unknown\"\n", body)
+
+		// published messages are delivered asynchronously
+		assert.Eventually(t, func() bool {
+			body, _ := testGet("http://example.com/message-bus-worker.php", handler, t)
+
+			return body == "Kevin"
+		}, time.Second, 10*time.Millisecond)
+	}, &testOptions{
+		nbParallelRequests: 1,
+		initOpts: []frankenphp.Option{
+			frankenphp.WithWorkers("messages", testDataDir+"message-bus-worker.php", 1, frankenphp.WithWorkerSubscriptions("news")),
+		},
+	})
+}
@@ -2,6 +2,7 @@ package frankenphp
 
 import (
 	"errors"
+	"strconv"
 	"sync"
 	"time"
 
@@ -12,6 +13,8 @@ const (
 	StopReasonCrash = iota
 	StopReasonRestart
 	StopReasonBootFailure // worker crashed before reaching frankenphp_handle_request
+	StopReasonMemoryLimit // worker restarted because its thread exceeded max_memory
+	StopReasonTimeout     // worker interrupted because a request exceeded its request timeout
 )
 
 type StopReason int
@@ -25,6 +28,8 @@ type Metrics interface {
 	StopWorker(name string, reason StopReason)
 	// TotalWorkers collects expected workers
 	TotalWorkers(name string, num int)
+	// WorkerServer collects the server a worker is scoped to, empty for global workers, used as the server label of its metrics
+	WorkerServer(name string, server string)
 	// TotalThreads collects total threads
 	TotalThreads(num int)
 	// StartRequest collects started requests
@@ -33,13 +38,65 @@ type Metrics interface {
 	StopRequest()
 	// StopWorkerRequest collects stopped worker requests
 	StopWorkerRequest(name string, duration time.Duration)
+	// StopRequestWithStats collects stopped requests with their status code, queue wait time and server, called instead of StopRequest
+	StopRequestWithStats(stats RequestStats)
+	// StopWorkerRequestWithStats collects stopped worker requests with their status code, queue wait time and server, called instead of StopWorkerRequest
+	StopWorkerRequestWithStats(stats RequestStats)
 	// StartWorkerRequest collects started worker requests
 	StartWorkerRequest(name string)
+	// StartServerRequest collects threads starting to handle a request of a server
+	StartServerRequest(server string)
+	// StopServerRequest collects threads done with a request of a server
+	StopServerRequest(server string)
+	// TotalServerThreads collects the number of regular threads dedicated to a server
+	TotalServerThreads(server string, num int)
+	// QueuedServerRequest collects regular requests of a server waiting for a thread
+	QueuedServerRequest(server string)
+	// DequeuedServerRequest collects regular requests of a server leaving the queue
+	DequeuedServerRequest(server string)
 	Shutdown()
 	QueuedWorkerRequest(name string)
 	DequeuedWorkerRequest(name string)
 	QueuedRequest()
 	DequeuedRequest()
+	// ShedWorkerRequest collects worker requests rejected because the queue is full
+	ShedWorkerRequest(name string)
+	// ShedRequest collects regular requests rejected because the queue is full
+	ShedRequest()
+	// SlowWorkerRequest collects worker requests running longer than slowlog_timeout
+	SlowWorkerRequest(name string)
+	// SlowRequest collects regular requests running longer than slowlog_timeout
+	SlowRequest()
+	// MemoryRestart collects regular threads restarted because they exceeded max_memory
+	MemoryRestart()
+	// StopScheduledTask collects the last run of a scheduled task, exitStatus is -1 if the task could not be started
+	StopScheduledTask(name string, exitStatus int, startedAt time.Time, duration time.Duration)
+	// CacheHit collects lookups of the shared cache finding a value
+	CacheHit()
+	// CacheMiss collects lookups of the shared cache finding no value
+	CacheMiss()
+	// CacheEviction collects entries of the shared cache evicted because it is full
+	CacheEviction()
+	// StartDetachedResponse collects responses starting to be drained to the client in detached output mode
+	StartDetachedResponse()
+	// StopDetachedResponse collects responses in detached output mode entirely sent to the client
+	StopDetachedResponse()
+	// DetachedOutputBuffered collects the bytes added to (positive) or drained from (negative) the detached output buffers
+	DetachedOutputBuffered(bytes int)
+}
+
+// RequestStats describes a request handled or rejected by FrankenPHP
+type RequestStats struct {
+	// Server is the name of the server, empty for the default server
+	Server string
+	// Worker is the name of the worker, empty for regular requests
+	Worker string
+	// Status is the HTTP status code of the response, 0 if the request has no HTTP response
+	Status int
+	// Duration is the total time spent by FrankenPHP on the request, including QueueWait
+	Duration time.Duration
+	// QueueWait is the time spent waiting for a free PHP thread
+	QueueWait time.Duration
 }
 
 type nullMetrics struct{}
@@ -56,6 +113,9 @@ func (n nullMetrics) StopWorker(string, StopReason) {
 func (n nullMetrics) TotalWorkers(string, int) {
 }
 
+func (n nullMetrics) WorkerServer(string, string) {
+}
+
 func (n nullMetrics) TotalThreads(int) {
 }
 
@@ -68,9 +128,30 @@ func (n nullMetrics) StopRequest() {
 func (n nullMetrics) StopWorkerRequest(string, time.Duration) {
 }
 
+func (n nullMetrics) StopRequestWithStats(RequestStats) {
+}
+
+func (n nullMetrics) StopWorkerRequestWithStats(RequestStats) {
+}
+
 func (n nullMetrics) StartWorkerRequest(string) {
 }
 
+func (n nullMetrics) StartServerRequest(string) {
+}
+
+func (n nullMetrics) StopServerRequest(string) {
+}
+
+func (n nullMetrics) TotalServerThreads(string, int) {
+}
+
+func (n nullMetrics) QueuedServerRequest(string) {
+}
+
+func (n nullMetrics) DequeuedServerRequest(string) {
+}
+
 func (n nullMetrics) Shutdown() {
 }
 
@@ -81,6 +162,24 @@ func (n nullMetrics) DequeuedWorkerRequest(string) {}
 func (n nullMetrics) QueuedRequest()   {}
 func (n nullMetrics) DequeuedRequest() {}
 
+func (n nullMetrics) ShedWorkerRequest(string) {}
+func (n nullMetrics) ShedRequest()             {}
+
+func (n nullMetrics) SlowWorkerRequest(string) {}
+func (n nullMetrics) SlowRequest()             {}
+
+func (n nullMetrics) MemoryRestart() {}
+
+func (n nullMetrics) StopScheduledTask(string, int, time.Time, time.Duration) {}
+
+func (n nullMetrics) CacheHit()      {}
+func (n nullMetrics) CacheMiss()     {}
+func (n nullMetrics) CacheEviction() {}
+
+func (n nullMetrics) StartDetachedResponse()     {}
+func (n nullMetrics) StopDetachedResponse()      {}
+func (n nullMetrics) DetachedOutputBuffered(int) {}
+
 type PrometheusMetrics struct {
 	registry           prometheus.Registerer
 	totalThreads       prometheus.Gauge
@@ -90,11 +189,33 @@ type PrometheusMetrics struct {
 	readyWorkers       *prometheus.GaugeVec
 	workerCrashes      *prometheus.CounterVec
 	workerRestarts     *prometheus.CounterVec
+	workerMemRestarts  *prometheus.CounterVec
+	workerTimeouts     *prometheus.CounterVec
 	workerRequestTime  *prometheus.CounterVec
 	workerRequestCount *prometheus.CounterVec
 	workerQueueDepth   *prometheus.GaugeVec
+	workerShedRequests *prometheus.CounterVec
+	workerSlowRequests *prometheus.CounterVec
 	queueDepth         prometheus.Gauge
-	mu                 sync.RWMutex
+	shedRequests       prometheus.Counter
+	slowRequests       prometheus.Counter
+	memoryRestarts     prometheus.Counter
+	requestDuration    *prometheus.HistogramVec
+	queueWait          *prometheus.HistogramVec
+	serverBusyThreads  *prometheus.GaugeVec
+	serverThreads      *prometheus.GaugeVec
+	serverQueueDepth   *prometheus.GaugeVec
+	taskLastRun        *prometheus.GaugeVec
+	taskLastStatus     *prometheus.GaugeVec
+	taskLastDuration   *prometheus.GaugeVec
+	cacheHits          prometheus.Counter
+	cacheMisses        prometheus.Counter
+	cacheEvictions     prometheus.Counter
+	detachedResponses  prometheus.Gauge
+	detachedBuffered   prometheus.Gauge
+	// the server of each worker, empty for global workers
+	workerServers map[string]string
+	mu            sync.RWMutex
 }
 
 func (m *PrometheusMetrics) StartWorker(name string) {
@@ -108,7 +229,7 @@ func (m *PrometheusMetrics) StartWorker(name string) {
 		return
 	}
 
-	m.totalWorkers.WithLabelValues(name).Inc()
+	m.totalWorkers.WithLabelValues(name, m.workerServers[name]).Inc()
 }
 
 func (m *PrometheusMetrics) ReadyWorker(name string) {
@@ -119,7 +240,7 @@ func (m *PrometheusMetrics) ReadyWorker(name string) {
 		return
 	}
 
-	m.readyWorkers.WithLabelValues(name).Inc()
+	m.readyWorkers.WithLabelValues(name, m.workerServers[name]).Inc()
 }
 
 func (m *PrometheusMetrics) StopWorker(name string, reason StopReason) {
@@ -133,18 +254,22 @@ func (m *PrometheusMetrics) StopWorker(name string, reason StopReason) {
 		return
 	}
 
-	m.totalWorkers.WithLabelValues(name).Dec()
+	m.totalWorkers.WithLabelValues(name, m.workerServers[name]).Dec()
 
 	// only decrement readyWorkers if the worker actually reached frankenphp_handle_request
 	if reason != StopReasonBootFailure {
-		m.readyWorkers.WithLabelValues(name).Dec()
+		m.readyWorkers.WithLabelValues(name, m.workerServers[name]).Dec()
 	}
 
 	switch reason {
 	case StopReasonCrash, StopReasonBootFailure:
-		m.workerCrashes.WithLabelValues(name).Inc()
+		m.workerCrashes.WithLabelValues(name, m.workerServers[name]).Inc()
 	case StopReasonRestart:
-		m.workerRestarts.WithLabelValues(name).Inc()
+		m.workerRestarts.WithLabelValues(name, m.workerServers[name]).Inc()
+	case StopReasonMemoryLimit:
+		m.workerMemRestarts.WithLabelValues(name, m.workerServers[name]).Inc()
+	case StopReasonTimeout:
+		m.workerTimeouts.WithLabelValues(name, m.workerServers[name]).Inc()
 	}
 }
 
@@ -153,14 +278,14 @@ func (m *PrometheusMetrics) TotalWorkers(string, int) {
 	defer m.mu.Unlock()
 
 	const ns, sub = "frankenphp", "worker"
-	basicLabels := []string{"worker"}
+	serverLabels := []string{"worker", "server"}
 
 	if m.totalWorkers == nil {
 		m.totalWorkers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Namespace: ns,
 			Name:      "total_workers",
 			Help:      "Total number of PHP workers for this worker",
-		}, basicLabels)
+		}, serverLabels)
 		if err := m.registry.Register(m.totalWorkers); err != nil &&
 			!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
 			panic(err)
@@ -172,7 +297,7 @@ func (m *PrometheusMetrics) TotalWorkers(string, int) {
 			Namespace: ns,
 			Name:      "ready_workers",
 			Help:      "Running workers that have successfully called frankenphp_handle_request at least once",
-		}, basicLabels)
+		}, serverLabels)
 		if err := m.registry.Register(m.readyWorkers); err != nil &&
 			!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
 			panic(err)
@@ -184,7 +309,7 @@ func (m *PrometheusMetrics) TotalWorkers(string, int) {
 			Namespace: ns,
 			Name:      "busy_workers",
 			Help:      "Number of busy PHP workers for this worker",
-		}, basicLabels)
+		}, serverLabels)
 		if err := m.registry.Register(m.busyWorkers); err != nil &&
 			!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
 			panic(err)
@@ -197,7 +322,7 @@ func (m *PrometheusMetrics) TotalWorkers(string, int) {
 			Subsystem: sub,
 			Name:      "crashes",
 			Help:      "Number of PHP worker crashes for this worker",
-		}, basicLabels)
+		}, serverLabels)
 		if err := m.registry.Register(m.workerCrashes); err != nil &&
 			!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
 			panic(err)
@@ -210,19 +335,45 @@ func (m *PrometheusMetrics) TotalWorkers(string, int) {
 			Subsystem: sub,
 			Name:      "restarts",
 			Help:      "Number of PHP worker restarts for this worker",
-		}, basicLabels)
+		}, serverLabels)
 		if err := m.registry.Register(m.workerRestarts); err != nil &&
 			!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
 			panic(err)
 		}
 	}
 
+	if m.workerMemRestarts == nil {
+		m.workerMemRestarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Subsystem: sub,
+			Name:      "memory_restarts",
+			Help:      "Number of PHP worker restarts caused by a thread exceeding max_memory for this worker",
+		}, serverLabels)
+		if err := m.registry.Register(m.workerMemRestarts); err != nil &&
+			!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+			panic(err)
+		}
+	}
+
+	if m.workerTimeouts == nil {
+		m.workerTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Subsystem: sub,
+			Name:      "timeouts",
+			Help:      "Number of PHP worker restarts caused by a request exceeding its request timeout for this worker",
+		}, serverLabels)
+		if err := m.registry.Register(m.workerTimeouts); err != nil &&
+			!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+			panic(err)
+		}
+	}
+
 	if m.workerRequestTime == nil {
 		m.workerRequestTime = prometheus.NewCounterVec(prometheus.CounterOpts{
 			Namespace: ns,
 			Subsystem: sub,
 			Name:      "request_time",
-		}, basicLabels)
+		}, serverLabels)
 		if err := m.registry.Register(m.workerRequestTime); err != nil &&
 			!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
 			panic(err)
@@ -234,7 +385,7 @@ func (m *PrometheusMetrics) TotalWorkers(string, int) {
 			Namespace: ns,
 			Subsystem: sub,
 			Name:      "request_count",
-		}, basicLabels)
+		}, serverLabels)
 		if err := m.registry.Register(m.workerRequestCount); err != nil &&
 			!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
 			panic(err)
@@ -246,12 +397,48 @@ func (m *PrometheusMetrics) TotalWorkers(string, int) {
 			Namespace: "frankenphp",
 			Subsystem: sub,
 			Name:      "queue_depth",
-		}, basicLabels)
+		}, serverLabels)
 		if err := m.registry.Register(m.workerQueueDepth); err != nil &&
 			!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
 			panic(err)
 		}
 	}
+
+	if m.workerShedRequests == nil {
+		m.workerShedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Subsystem: sub,
+			Name:      "shed_requests",
+			Help:      "Number of requests rejected because the queue of this worker is full",
+		}, serverLabels)
+		if err := m.registry.Register(m.workerShedRequests); err != nil &&
+			!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+			panic(err)
+		}
+	}
+
+	if m.workerSlowRequests == nil {
+		m.workerSlowRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Subsystem: sub,
+			Name:      "slow_requests",
+			Help:      "Number of requests of this worker running longer than slowlog_timeout",
+		}, serverLabels)
+		if err := m.registry.Register(m.workerSlowRequests); err != nil &&
+			!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+			panic(err)
+		}
+	}
+}
+
+func (m *PrometheusMetrics) WorkerServer(name string, server string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.workerServers == nil {
+		m.workerServers = make(map[string]string)
+	}
+	m.workerServers[name] = server
 }
 
 func (m *PrometheusMetrics) TotalThreads(num int) {
@@ -283,9 +470,86 @@ func (m *PrometheusMetrics) StopWorkerRequest(name string, duration time.Duratio
 		return
 	}
 
-	m.workerRequestCount.WithLabelValues(name).Inc()
-	m.busyWorkers.WithLabelValues(name).Dec()
-	m.workerRequestTime.WithLabelValues(name).Add(duration.Seconds())
+	m.workerRequestCount.WithLabelValues(name, m.workerServers[name]).Inc()
+	m.busyWorkers.WithLabelValues(name, m.workerServers[name]).Dec()
+	m.workerRequestTime.WithLabelValues(name, m.workerServers[name]).Add(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) StartServerRequest(server string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.serverBusyThreads == nil {
+		return
+	}
+	m.serverBusyThreads.WithLabelValues(server).Inc()
+}
+
+func (m *PrometheusMetrics) StopServerRequest(server string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.serverBusyThreads == nil {
+		return
+	}
+	m.serverBusyThreads.WithLabelValues(server).Dec()
+}
+
+func (m *PrometheusMetrics) TotalServerThreads(server string, num int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.serverThreads == nil {
+		return
+	}
+	m.serverThreads.WithLabelValues(server).Set(float64(num))
+}
+
+func (m *PrometheusMetrics) QueuedServerRequest(server string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.serverQueueDepth == nil {
+		return
+	}
+	m.serverQueueDepth.WithLabelValues(server).Inc()
+}
+
+func (m *PrometheusMetrics) DequeuedServerRequest(server string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.serverQueueDepth == nil {
+		return
+	}
+	m.serverQueueDepth.WithLabelValues(server).Dec()
+}
+
+func (m *PrometheusMetrics) StopRequestWithStats(stats RequestStats) {
+	m.StopRequest()
+	m.observeRequest(stats)
+}
+
+func (m *PrometheusMetrics) StopWorkerRequestWithStats(stats RequestStats) {
+	m.StopWorkerRequest(stats.Worker, stats.Duration)
+	m.observeRequest(stats)
+}
+
+func (m *PrometheusMetrics) observeRequest(stats RequestStats) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.requestDuration == nil {
+		return
+	}
+
+	status := ""
+	if stats.Status != 0 {
+		status = strconv.Itoa(stats.Status)
+	}
+
+	m.requestDuration.WithLabelValues(stats.Server, stats.Worker, status).Observe(stats.Duration.Seconds())
+	m.queueWait.WithLabelValues(stats.Server, stats.Worker).Observe(stats.QueueWait.Seconds())
 }
 
 func (m *PrometheusMetrics) StartWorkerRequest(name string) {
@@ -295,7 +559,7 @@ func (m *PrometheusMetrics) StartWorkerRequest(name string) {
 	if m.busyWorkers == nil {
 		return
 	}
-	m.busyWorkers.WithLabelValues(name).Inc()
+	m.busyWorkers.WithLabelValues(name, m.workerServers[name]).Inc()
 }
 
 func (m *PrometheusMetrics) QueuedWorkerRequest(name string) {
@@ -305,7 +569,7 @@ func (m *PrometheusMetrics) QueuedWorkerRequest(name string) {
 	if m.workerQueueDepth == nil {
 		return
 	}
-	m.workerQueueDepth.WithLabelValues(name).Inc()
+	m.workerQueueDepth.WithLabelValues(name, m.workerServers[name]).Inc()
 }
 
 func (m *PrometheusMetrics) DequeuedWorkerRequest(name string) {
@@ -315,7 +579,7 @@ func (m *PrometheusMetrics) DequeuedWorkerRequest(name string) {
 	if m.workerQueueDepth == nil {
 		return
 	}
-	m.workerQueueDepth.WithLabelValues(name).Dec()
+	m.workerQueueDepth.WithLabelValues(name, m.workerServers[name]).Dec()
 }
 
 func (m *PrometheusMetrics) QueuedRequest() {
@@ -332,6 +596,101 @@ func (m *PrometheusMetrics) DequeuedRequest() {
 	m.queueDepth.Dec()
 }
 
+func (m *PrometheusMetrics) ShedWorkerRequest(name string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.workerShedRequests == nil {
+		return
+	}
+	m.workerShedRequests.WithLabelValues(name, m.workerServers[name]).Inc()
+}
+
+func (m *PrometheusMetrics) ShedRequest() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.shedRequests.Inc()
+}
+
+func (m *PrometheusMetrics) SlowWorkerRequest(name string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.workerSlowRequests == nil {
+		return
+	}
+	m.workerSlowRequests.WithLabelValues(name, m.workerServers[name]).Inc()
+}
+
+func (m *PrometheusMetrics) SlowRequest() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.slowRequests.Inc()
+}
+
+func (m *PrometheusMetrics) MemoryRestart() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.memoryRestarts.Inc()
+}
+
+func (m *PrometheusMetrics) StopScheduledTask(name string, exitStatus int, startedAt time.Time, duration time.Duration) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.taskLastRun == nil {
+		return
+	}
+	m.taskLastRun.WithLabelValues(name).Set(float64(startedAt.UnixNano()) / 1e9)
+	m.taskLastStatus.WithLabelValues(name).Set(float64(exitStatus))
+	m.taskLastDuration.WithLabelValues(name).Set(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) CacheHit() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.cacheHits.Inc()
+}
+
+func (m *PrometheusMetrics) CacheMiss() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.cacheMisses.Inc()
+}
+
+func (m *PrometheusMetrics) CacheEviction() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.cacheEvictions.Inc()
+}
+
+func (m *PrometheusMetrics) StartDetachedResponse() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.detachedResponses.Inc()
+}
+
+func (m *PrometheusMetrics) StopDetachedResponse() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.detachedResponses.Dec()
+}
+
+func (m *PrometheusMetrics) DetachedOutputBuffered(bytes int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.detachedBuffered.Add(float64(bytes))
+}
+
 func (m *PrometheusMetrics) Shutdown() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -339,6 +698,23 @@ func (m *PrometheusMetrics) Shutdown() {
 	m.registry.Unregister(m.totalThreads)
 	m.registry.Unregister(m.busyThreads)
 	m.registry.Unregister(m.queueDepth)
+	m.registry.Unregister(m.shedRequests)
+	m.registry.Unregister(m.slowRequests)
+	m.registry.Unregister(m.memoryRestarts)
+	m.registry.Unregister(m.requestDuration)
+	m.registry.Unregister(m.queueWait)
+	m.registry.Unregister(m.serverBusyThreads)
+	m.registry.Unregister(m.serverThreads)
+	m.registry.Unregister(m.serverQueueDepth)
+	m.registry.Unregister(m.taskLastRun)
+	m.registry.Unregister(m.taskLastStatus)
+	m.registry.Unregister(m.taskLastDuration)
+	m.registry.Unregister(m.cacheHits)
+	m.registry.Unregister(m.cacheMisses)
+	m.registry.Unregister(m.cacheEvictions)
+	m.registry.Unregister(m.detachedResponses)
+	m.registry.Unregister(m.detachedBuffered)
+	m.workerServers = nil
 
 	if m.totalWorkers != nil {
 		m.registry.Unregister(m.totalWorkers)
@@ -364,6 +740,14 @@ func (m *PrometheusMetrics) Shutdown() {
 		m.registry.Unregister(m.workerRestarts)
 	}
 
+	if m.workerMemRestarts != nil {
+		m.registry.Unregister(m.workerMemRestarts)
+	}
+
+	if m.workerTimeouts != nil {
+		m.registry.Unregister(m.workerTimeouts)
+	}
+
 	if m.readyWorkers != nil {
 		m.registry.Unregister(m.readyWorkers)
 	}
@@ -371,6 +755,14 @@ func (m *PrometheusMetrics) Shutdown() {
 	if m.workerQueueDepth != nil {
 		m.registry.Unregister(m.workerQueueDepth)
 	}
+
+	if m.workerShedRequests != nil {
+		m.registry.Unregister(m.workerShedRequests)
+	}
+
+	if m.workerSlowRequests != nil {
+		m.registry.Unregister(m.workerSlowRequests)
+	}
 }
 
 func NewPrometheusMetrics(registry prometheus.Registerer) *PrometheusMetrics {
@@ -392,14 +784,84 @@ func NewPrometheusMetrics(registry prometheus.Registerer) *PrometheusMetrics {
 			Name: "frankenphp_queue_depth",
 			Help: "Number of regular queued requests",
 		}),
+		shedRequests: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "frankenphp_shed_requests",
+			Help: "Number of regular requests rejected because the queue is full",
+		}),
+		slowRequests: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "frankenphp_slow_requests",
+			Help: "Number of regular requests running longer than slowlog_timeout",
+		}),
+		memoryRestarts: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "frankenphp_memory_restarts",
+			Help: "Number of regular PHP thread restarts caused by a thread exceeding max_memory",
+		}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "frankenphp_request_duration_seconds",
+			Help:    "Time spent by FrankenPHP on requests, including the time spent waiting for a thread",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"server", "worker", "status"}),
+		queueWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "frankenphp_queue_wait_seconds",
+			Help:    "Time spent by requests waiting for a free PHP thread",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"server", "worker"}),
+		serverBusyThreads: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "frankenphp_server_busy_threads",
+			Help: "Number of PHP threads currently handling a request of this server",
+		}, []string{"server"}),
+		serverThreads: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "frankenphp_server_threads",
+			Help: "Number of regular PHP threads dedicated to this server",
+		}, []string{"server"}),
+		serverQueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "frankenphp_server_queue_depth",
+			Help: "Number of regular requests of this server waiting for a PHP thread",
+		}, []string{"server"}),
+		taskLastRun: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "frankenphp_scheduled_task_last_run_timestamp_seconds",
+			Help: "Start time of the last run of this scheduled task",
+		}, []string{"task"}),
+		taskLastStatus: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "frankenphp_scheduled_task_last_exit_status",
+			Help: "Exit status of the last run of this scheduled task, -1 if it could not be started",
+		}, []string{"task"}),
+		taskLastDuration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "frankenphp_scheduled_task_last_duration_seconds",
+			Help: "Duration of the last run of this scheduled task",
+		}, []string{"task"}),
+		cacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "frankenphp_cache_hits",
+			Help: "Number of lookups of the shared cache finding a value",
+		}),
+		cacheMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "frankenphp_cache_misses",
+			Help: "Number of lookups of the shared cache finding no value",
+		}),
+		cacheEvictions: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "frankenphp_cache_evictions",
+			Help: "Number of entries of the shared cache evicted because it is full",
+		}),
+		detachedResponses: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "frankenphp_detached_responses",
+			Help: "Number of responses being sent to the client in detached output mode",
+		}),
+		detachedBuffered: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "frankenphp_detached_output_buffered_bytes",
+			Help: "Number of bytes of output buffered in detached output mode and not yet sent to the client",
+		}),
 		totalWorkers:       nil,
 		busyWorkers:        nil,
 		workerRequestTime:  nil,
 		workerRequestCount: nil,
 		workerRestarts:     nil,
+		workerMemRestarts:  nil,
+		workerTimeouts:     nil,
 		workerCrashes:      nil,
 		readyWorkers:       nil,
 		workerQueueDepth:   nil,
+		workerShedRequests: nil,
+		workerSlowRequests: nil,
 	}
 
 	if err := m.registry.Register(m.totalThreads); err != nil &&
@@ -417,5 +879,85 @@ func NewPrometheusMetrics(registry prometheus.Registerer) *PrometheusMetrics {
 		panic(err)
 	}
 
+	if err := m.registry.Register(m.shedRequests); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.slowRequests); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.memoryRestarts); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.requestDuration); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.queueWait); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.serverBusyThreads); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.serverThreads); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.serverQueueDepth); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.taskLastRun); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.taskLastStatus); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.taskLastDuration); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.cacheHits); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.cacheMisses); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.cacheEvictions); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.detachedResponses); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
+	if err := m.registry.Register(m.detachedBuffered); err != nil &&
+		!errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		panic(err)
+	}
+
 	return m
 }
@@ -27,6 +27,7 @@ func createPrometheusMetrics() *PrometheusMetrics {
 		totalThreads: prometheus.NewGauge(prometheus.GaugeOpts{Name: "frankenphp_total_threads"}),
 		busyThreads:  prometheus.NewGauge(prometheus.GaugeOpts{Name: "frankenphp_busy_threads"}),
 		queueDepth:   prometheus.NewGauge(prometheus.GaugeOpts{Name: "frankenphp_queue_depth"}),
+		shedRequests: prometheus.NewCounter(prometheus.CounterOpts{Name: "frankenphp_shed_requests"}),
 	}
 }
 
@@ -81,7 +82,7 @@ func TestPrometheusMetrics_StopWorkerRequest(t *testing.T) {
 				# TYPE frankenphp_worker_request_count counter
 			`,
 			expect: `
-				frankenphp_worker_request_count{worker="test_worker"} 1
+				frankenphp_worker_request_count{server="",worker="test_worker"} 1
 			`,
 		},
 		{
@@ -92,7 +93,7 @@ func TestPrometheusMetrics_StopWorkerRequest(t *testing.T) {
 				# TYPE frankenphp_busy_workers gauge
 			`,
 			expect: `
-				frankenphp_busy_workers{worker="test_worker"} -1
+				frankenphp_busy_workers{server="",worker="test_worker"} -1
 			`,
 		},
 		{
@@ -103,7 +104,7 @@ func TestPrometheusMetrics_StopWorkerRequest(t *testing.T) {
 				# TYPE frankenphp_worker_request_time counter
 			`,
 			expect: `
-				frankenphp_worker_request_time{worker="test_worker"} 2
+				frankenphp_worker_request_time{server="",worker="test_worker"} 2
 			`,
 		},
 	}
@@ -135,7 +136,7 @@ func TestPrometheusMetrics_StartWorkerRequest(t *testing.T) {
 				# TYPE frankenphp_busy_workers gauge
 			`,
 			expect: `
-				frankenphp_busy_workers{worker="test_worker"} 1
+				frankenphp_busy_workers{server="",worker="test_worker"} 1
 			`,
 		},
 	}
@@ -178,7 +179,7 @@ func TestPrometheusMetrics_TestStopReasonCrash(t *testing.T) {
 				# TYPE frankenphp_total_workers gauge
 			`,
 			expect: `
-				frankenphp_total_workers{worker="test_worker"} -1
+				frankenphp_total_workers{server="",worker="test_worker"} -1
 			`,
 		},
 		{
@@ -189,7 +190,7 @@ func TestPrometheusMetrics_TestStopReasonCrash(t *testing.T) {
 				# TYPE frankenphp_ready_workers gauge
 			`,
 			expect: `
-				frankenphp_ready_workers{worker="test_worker"} -1
+				frankenphp_ready_workers{server="",worker="test_worker"} -1
 			`,
 		},
 		{
@@ -200,7 +201,7 @@ func TestPrometheusMetrics_TestStopReasonCrash(t *testing.T) {
 				# TYPE frankenphp_worker_crashes counter
 			`,
 			expect: `
-				frankenphp_worker_crashes{worker="test_worker"} 1
+				frankenphp_worker_crashes{server="",worker="test_worker"} 1
 			`,
 		},
 	}
@@ -212,3 +213,212 @@ func TestPrometheusMetrics_TestStopReasonCrash(t *testing.T) {
 
 	}
 }
+
+func TestPrometheusMetrics_TestStopReasonMemoryLimit(t *testing.T) {
+	m := createPrometheusMetrics()
+	m.TotalWorkers("test_worker", 2)
+	m.StopWorker("test_worker", StopReasonMemoryLimit)
+
+	require.NoError(t, testutil.CollectAndCompare(m.workerRestarts, strings.NewReader(""))) // memory restarts are only counted once
+	require.NoError(t, testutil.CollectAndCompare(m.workerMemRestarts, strings.NewReader(`
+		# HELP frankenphp_worker_memory_restarts Number of PHP worker restarts caused by a thread exceeding max_memory for this worker
+		# TYPE frankenphp_worker_memory_restarts counter
+		frankenphp_worker_memory_restarts{server="",worker="test_worker"} 1
+	`)))
+}
+
+func TestPrometheusMetrics_TestStopReasonTimeout(t *testing.T) {
+	m := createPrometheusMetrics()
+	m.TotalWorkers("test_worker", 2)
+	m.StopWorker("test_worker", StopReasonTimeout)
+
+	require.NoError(t, testutil.CollectAndCompare(m.workerCrashes, strings.NewReader("")))
+	require.NoError(t, testutil.CollectAndCompare(m.workerTimeouts, strings.NewReader(`
+		# HELP frankenphp_worker_timeouts Number of PHP worker restarts caused by a request exceeding its request timeout for this worker
+		# TYPE frankenphp_worker_timeouts counter
+		frankenphp_worker_timeouts{server="",worker="test_worker"} 1
+	`)))
+}
+
+func TestPrometheusMetrics_MemoryRestart(t *testing.T) {
+	m := NewPrometheusMetrics(prometheus.NewRegistry())
+	m.MemoryRestart()
+
+	require.NoError(t, testutil.CollectAndCompare(m.memoryRestarts, strings.NewReader(`
+		# HELP frankenphp_memory_restarts Number of regular PHP thread restarts caused by a thread exceeding max_memory
+		# TYPE frankenphp_memory_restarts counter
+		frankenphp_memory_restarts 1
+	`)))
+}
+
+func TestPrometheusMetrics_SlowRequests(t *testing.T) {
+	m := NewPrometheusMetrics(prometheus.NewRegistry())
+	m.TotalWorkers("test_worker", 2)
+	m.SlowWorkerRequest("test_worker")
+	m.SlowRequest()
+	m.SlowRequest()
+
+	require.NoError(t, testutil.CollectAndCompare(m.workerSlowRequests, strings.NewReader(`
+		# HELP frankenphp_worker_slow_requests Number of requests of this worker running longer than slowlog_timeout
+		# TYPE frankenphp_worker_slow_requests counter
+		frankenphp_worker_slow_requests{server="",worker="test_worker"} 1
+	`)))
+	require.NoError(t, testutil.CollectAndCompare(m.slowRequests, strings.NewReader(`
+		# HELP frankenphp_slow_requests Number of regular requests running longer than slowlog_timeout
+		# TYPE frankenphp_slow_requests counter
+		frankenphp_slow_requests 2
+	`)))
+}
+
+func TestPrometheusMetrics_RequestStats(t *testing.T) {
+	m := NewPrometheusMetrics(prometheus.NewRegistry())
+	m.TotalWorkers("test_worker", 2)
+	m.StartWorkerRequest("test_worker")
+	m.StopWorkerRequestWithStats(RequestStats{Server: "app", Worker: "test_worker", Status: 200, Duration: 200 * time.Millisecond, QueueWait: 20 * time.Millisecond})
+	m.StartRequest()
+	m.StopRequestWithStats(RequestStats{Status: 503, Duration: 3 * time.Second, QueueWait: 3 * time.Second})
+
+	require.NoError(t, testutil.CollectAndCompare(m.requestDuration, strings.NewReader(`
+		# HELP frankenphp_request_duration_seconds Time spent by FrankenPHP on requests, including the time spent waiting for a thread
+		# TYPE frankenphp_request_duration_seconds histogram
+		frankenphp_request_duration_seconds_bucket{server="",status="503",worker="",le="0.005"} 0
+		frankenphp_request_duration_seconds_bucket{server="",status="503",worker="",le="0.01"} 0
+		frankenphp_request_duration_seconds_bucket{server="",status="503",worker="",le="0.025"} 0
+		frankenphp_request_duration_seconds_bucket{server="",status="503",worker="",le="0.05"} 0
+		frankenphp_request_duration_seconds_bucket{server="",status="503",worker="",le="0.1"} 0
+		frankenphp_request_duration_seconds_bucket{server="",status="503",worker="",le="0.25"} 0
+		frankenphp_request_duration_seconds_bucket{server="",status="503",worker="",le="0.5"} 0
+		frankenphp_request_duration_seconds_bucket{server="",status="503",worker="",le="1"} 0
+		frankenphp_request_duration_seconds_bucket{server="",status="503",worker="",le="2.5"} 0
+		frankenphp_request_duration_seconds_bucket{server="",status="503",worker="",le="5"} 1
+		frankenphp_request_duration_seconds_bucket{server="",status="503",worker="",le="10"} 1
+		frankenphp_request_duration_seconds_bucket{server="",status="503",worker="",le="+Inf"} 1
+		frankenphp_request_duration_seconds_sum{server="",status="503",worker=""} 3
+		frankenphp_request_duration_seconds_count{server="",status="503",worker=""} 1
+		frankenphp_request_duration_seconds_bucket{server="app",status="200",worker="test_worker",le="0.005"} 0
+		frankenphp_request_duration_seconds_bucket{server="app",status="200",worker="test_worker",le="0.01"} 0
+		frankenphp_request_duration_seconds_bucket{server="app",status="200",worker="test_worker",le="0.025"} 0
+		frankenphp_request_duration_seconds_bucket{server="app",status="200",worker="test_worker",le="0.05"} 0
+		frankenphp_request_duration_seconds_bucket{server="app",status="200",worker="test_worker",le="0.1"} 0
+		frankenphp_request_duration_seconds_bucket{server="app",status="200",worker="test_worker",le="0.25"} 1
+		frankenphp_request_duration_seconds_bucket{server="app",status="200",worker="test_worker",le="0.5"} 1
+		frankenphp_request_duration_seconds_bucket{server="app",status="200",worker="test_worker",le="1"} 1
+		frankenphp_request_duration_seconds_bucket{server="app",status="200",worker="test_worker",le="2.5"} 1
+		frankenphp_request_duration_seconds_bucket{server="app",status="200",worker="test_worker",le="5"} 1
+		frankenphp_request_duration_seconds_bucket{server="app",status="200",worker="test_worker",le="10"} 1
+		frankenphp_request_duration_seconds_bucket{server="app",status="200",worker="test_worker",le="+Inf"} 1
+		frankenphp_request_duration_seconds_sum{server="app",status="200",worker="test_worker"} 0.2
+		frankenphp_request_duration_seconds_count{server="app",status="200",worker="test_worker"} 1
+	`)))
+	require.Equal(t, 2, testutil.CollectAndCount(m.queueWait, "frankenphp_queue_wait_seconds"))
+	require.InDelta(t, 0.2, testutil.ToFloat64(m.workerRequestTime.WithLabelValues("test_worker", "")), 1e-9)
+}
+
+func TestPrometheusMetrics_Servers(t *testing.T) {
+	m := NewPrometheusMetrics(prometheus.NewRegistry())
+	m.TotalWorkers("test_worker", 2)
+	m.WorkerServer("test_worker", "app")
+	m.StartServerRequest("app")
+	m.StartServerRequest("app")
+	m.StopServerRequest("app")
+	m.TotalServerThreads("app", 4)
+	m.QueuedServerRequest("app")
+
+	m.StartWorkerRequest("test_worker")
+	m.QueuedWorkerRequest("test_worker")
+
+	require.NoError(t, testutil.CollectAndCompare(m.busyWorkers, strings.NewReader(`
+		# HELP frankenphp_busy_workers Number of busy PHP workers for this worker
+		# TYPE frankenphp_busy_workers gauge
+		frankenphp_busy_workers{server="app",worker="test_worker"} 1
+	`)))
+	require.NoError(t, testutil.CollectAndCompare(m.workerQueueDepth, strings.NewReader(`
+		# HELP frankenphp_worker_queue_depth
+		# TYPE frankenphp_worker_queue_depth gauge
+		frankenphp_worker_queue_depth{server="app",worker="test_worker"} 1
+	`)))
+	require.NoError(t, testutil.CollectAndCompare(m.serverBusyThreads, strings.NewReader(`
+		# HELP frankenphp_server_busy_threads Number of PHP threads currently handling a request of this server
+		# TYPE frankenphp_server_busy_threads gauge
+		frankenphp_server_busy_threads{server="app"} 1
+	`)))
+	require.NoError(t, testutil.CollectAndCompare(m.serverThreads, strings.NewReader(`
+		# HELP frankenphp_server_threads Number of regular PHP threads dedicated to this server
+		# TYPE frankenphp_server_threads gauge
+		frankenphp_server_threads{server="app"} 4
+	`)))
+	require.NoError(t, testutil.CollectAndCompare(m.serverQueueDepth, strings.NewReader(`
+		# HELP frankenphp_server_queue_depth Number of regular requests of this server waiting for a PHP thread
+		# TYPE frankenphp_server_queue_depth gauge
+		frankenphp_server_queue_depth{server="app"} 1
+	`)))
+}
+
+func TestPrometheusMetrics_ScheduledTasks(t *testing.T) {
+	m := NewPrometheusMetrics(prometheus.NewRegistry())
+	m.StopScheduledTask("cleanup", 0, time.Unix(1700000000, 0), 1500*time.Millisecond)
+	m.StopScheduledTask("report", 2, time.Unix(1700000060, 0), time.Second)
+
+	require.NoError(t, testutil.CollectAndCompare(m.taskLastRun, strings.NewReader(`
+		# HELP frankenphp_scheduled_task_last_run_timestamp_seconds Start time of the last run of this scheduled task
+		# TYPE frankenphp_scheduled_task_last_run_timestamp_seconds gauge
+		frankenphp_scheduled_task_last_run_timestamp_seconds{task="cleanup"} 1.7e+09
+		frankenphp_scheduled_task_last_run_timestamp_seconds{task="report"} 1.70000006e+09
+	`)))
+	require.NoError(t, testutil.CollectAndCompare(m.taskLastStatus, strings.NewReader(`
+		# HELP frankenphp_scheduled_task_last_exit_status Exit status of the last run of this scheduled task, -1 if it could not be started
+		# TYPE frankenphp_scheduled_task_last_exit_status gauge
+		frankenphp_scheduled_task_last_exit_status{task="cleanup"} 0
+		frankenphp_scheduled_task_last_exit_status{task="report"} 2
+	`)))
+	require.NoError(t, testutil.CollectAndCompare(m.taskLastDuration, strings.NewReader(`
+		# HELP frankenphp_scheduled_task_last_duration_seconds Duration of the last run of this scheduled task
+		# TYPE frankenphp_scheduled_task_last_duration_seconds gauge
+		frankenphp_scheduled_task_last_duration_seconds{task="cleanup"} 1.5
+		frankenphp_scheduled_task_last_duration_seconds{task="report"} 1
+	`)))
+}
+
+func TestPrometheusMetrics_Cache(t *testing.T) {
+	m := NewPrometheusMetrics(prometheus.NewRegistry())
+	m.CacheHit()
+	m.CacheHit()
+	m.CacheMiss()
+	m.CacheEviction()
+
+	require.NoError(t, testutil.CollectAndCompare(m.cacheHits, strings.NewReader(`
+		# HELP frankenphp_cache_hits Number of lookups of the shared cache finding a value
+		# TYPE frankenphp_cache_hits counter
+		frankenphp_cache_hits 2
+	`)))
+	require.NoError(t, testutil.CollectAndCompare(m.cacheMisses, strings.NewReader(`
+		# HELP frankenphp_cache_misses Number of lookups of the shared cache finding no value
+		# TYPE frankenphp_cache_misses counter
+		frankenphp_cache_misses 1
+	`)))
+	require.NoError(t, testutil.CollectAndCompare(m.cacheEvictions, strings.NewReader(`
+		# HELP frankenphp_cache_evictions Number of entries of the shared cache evicted because it is full
+		# TYPE frankenphp_cache_evictions counter
+		frankenphp_cache_evictions 1
+	`)))
+}
+
+func TestPrometheusMetrics_DetachedOutput(t *testing.T) {
+	m := NewPrometheusMetrics(prometheus.NewRegistry())
+	m.StartDetachedResponse()
+	m.StartDetachedResponse()
+	m.StopDetachedResponse()
+	m.DetachedOutputBuffered(1024)
+	m.DetachedOutputBuffered(-512)
+
+	require.NoError(t, testutil.CollectAndCompare(m.detachedResponses, strings.NewReader(`
+		# HELP frankenphp_detached_responses Number of responses being sent to the client in detached output mode
+		# TYPE frankenphp_detached_responses gauge
+		frankenphp_detached_responses 1
+	`)))
+	require.NoError(t, testutil.CollectAndCompare(m.detachedBuffered, strings.NewReader(`
+		# HELP frankenphp_detached_output_buffered_bytes Number of bytes of output buffered in detached output mode and not yet sent to the client
+		# TYPE frankenphp_detached_output_buffered_bytes gauge
+		frankenphp_detached_output_buffered_bytes 512
+	`)))
+}
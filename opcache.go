@@ -0,0 +1,76 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"sync"
+	"unsafe"
+)
+
+// opcache_reset() is not thread-safe, the opcache is only reset when all threads are rebooted.
+// Threads restarted while the other ones keep running invalidate the scripts they may have cached instead:
+// the first thread to execute a script invalidates them, the others wait for it to be done.
+var opcacheInvalidation struct {
+	sync.Mutex
+	all   bool
+	files map[string]struct{}
+}
+
+// requestOpcacheInvalidation invalidates the given scripts before any thread executes a script again
+func requestOpcacheInvalidation(files []string) {
+	if len(files) == 0 {
+		return
+	}
+
+	opcacheInvalidation.Lock()
+	defer opcacheInvalidation.Unlock()
+
+	if opcacheInvalidation.files == nil {
+		opcacheInvalidation.files = make(map[string]struct{}, len(files))
+	}
+	for _, file := range files {
+		opcacheInvalidation.files[file] = struct{}{}
+	}
+
+	notifyOpcacheInvalidation()
+}
+
+// requestFullOpcacheInvalidation invalidates all cached scripts before any thread executes a script again,
+// it replaces opcache_reset() when threads are restarted while the other ones keep running
+func requestFullOpcacheInvalidation() {
+	opcacheInvalidation.Lock()
+	defer opcacheInvalidation.Unlock()
+
+	opcacheInvalidation.all = true
+
+	notifyOpcacheInvalidation()
+}
+
+func notifyOpcacheInvalidation() {
+	for _, thread := range phpThreads {
+		C.frankenphp_request_opcache_invalidation(C.uintptr_t(thread.threadIndex))
+	}
+}
+
+//export go_frankenphp_invalidate_opcache
+func go_frankenphp_invalidate_opcache(C.uintptr_t) {
+	opcacheInvalidation.Lock()
+	defer opcacheInvalidation.Unlock()
+
+	if opcacheInvalidation.all {
+		C.frankenphp_invalidate_all_scripts()
+	} else {
+		for file := range opcacheInvalidation.files {
+			C.frankenphp_invalidate_script((*C.char)(unsafe.Pointer(unsafe.StringData(file))), C.size_t(len(file)))
+		}
+	}
+	opcacheInvalidation.all = false
+	opcacheInvalidation.files = nil
+}
+
+//export go_frankenphp_worker_included_file
+func go_frankenphp_worker_included_file(threadIndex C.uintptr_t, file *C.char, length C.size_t) {
+	if handler, ok := phpThreads[threadIndex].handler.(*workerThread); ok {
+		handler.worker.addIncludedFile(C.GoStringN(file, C.int(length)))
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"log/slog"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // defaultMaxConsecutiveFailures is the default maximum number of consecutive failures before panicking
@@ -32,11 +34,25 @@ type opt struct {
 	workers     []workerOpt
 	logger      *slog.Logger
 	metrics     Metrics
+	tracer      trace.TracerProvider
 	phpIni      map[string]string
 	maxWaitTime time.Duration
 	maxIdleTime time.Duration
 	maxRequests int
 	servers     []*Server
+
+	scheduledTasks []*scheduledTask
+
+	maxThreadMemory int64
+	slowlogTimeout  time.Duration
+	cacheMaxSize    int64
+	sseHistorySize  int
+
+	scalingPolicy ScalingPolicy
+
+	maxQueueLength      int
+	queueFullStatus     int
+	queueFullRetryAfter time.Duration
 }
 
 type workerOpt struct {
@@ -51,12 +67,21 @@ type workerOpt struct {
 	watch                  []string
 	matchRequest           func(*http.Request) bool
 	maxConsecutiveFailures int
+	maxQueueLength         int
+	maxThreadMemory        int64
+	restartStrategy        RestartStrategy
+	topics                 []string
+	background             bool
+	websocket              bool
+	websocketOrigins       []string
 	extensionWorkers       *extensionWorkers
 	onThreadReady          func(int)
 	onThreadShutdown       func(int)
 	onServerStartup        func()
 	onServerShutdown       func()
 	server                 *Server
+	requestPriority        func(*http.Request) int
+	priorityClasses        []priorityClassOpt
 }
 
 // WithContext sets the main context to use.
@@ -93,6 +118,15 @@ func WithMetrics(m Metrics) Option {
 	}
 }
 
+// WithTracerProvider creates OpenTelemetry spans for queueing, script execution, worker boot and frankenphp_trace_span() calls.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *opt) error {
+		o.tracer = tp
+
+		return nil
+	}
+}
+
 // WithWorkers configures the PHP workers to start
 func WithWorkers(name, fileName string, num int, options ...WorkerOption) Option {
 	return func(o *opt) error {
@@ -165,6 +199,34 @@ func WithMaxWaitTime(maxWaitTime time.Duration) Option {
 	}
 }
 
+// WithMaxQueueLength configures the max number of requests that may be queued waiting for a thread (0 = unlimited).
+// The limit applies to the regular thread queue and to the queue of each worker that doesn't set its own.
+// When a queue is full, new requests are rejected immediately.
+func WithMaxQueueLength(maxQueueLength int) Option {
+	return func(o *opt) error {
+		if maxQueueLength < 0 {
+			return fmt.Errorf("max queue length must be >= 0, got %d", maxQueueLength)
+		}
+		o.maxQueueLength = maxQueueLength
+
+		return nil
+	}
+}
+
+// WithQueueFullResponse configures the status code and the Retry-After header sent when a request is rejected
+// because the queue is full. Default: 503 without Retry-After header.
+func WithQueueFullResponse(status int, retryAfter time.Duration) Option {
+	return func(o *opt) error {
+		if status < 400 || status > 599 {
+			return fmt.Errorf("queue full status must be an error status code, got %d", status)
+		}
+		o.queueFullStatus = status
+		o.queueFullRetryAfter = retryAfter
+
+		return nil
+	}
+}
+
 // WithMaxIdleTime configures the max time an autoscaled thread may be idle before being deactivated.
 func WithMaxIdleTime(maxIdleTime time.Duration) Option {
 	return func(o *opt) error {
@@ -174,6 +236,15 @@ func WithMaxIdleTime(maxIdleTime time.Duration) Option {
 	}
 }
 
+// WithScalingPolicy configures when threads are added or removed at runtime. Default: NewCPUScalingPolicy().
+func WithScalingPolicy(policy ScalingPolicy) Option {
+	return func(o *opt) error {
+		o.scalingPolicy = policy
+
+		return nil
+	}
+}
+
 // EXPERIMENTAL: WithMaxRequests sets the default max requests before restarting a PHP thread (0 = unlimited). Applies to regular and worker threads.
 func WithMaxRequests(maxRequests int) Option {
 	return func(o *opt) error {
@@ -183,6 +254,72 @@ func WithMaxRequests(maxRequests int) Option {
 	}
 }
 
+// WithSlowlogTimeout logs the PHP backtrace of requests running longer than the given duration (0 = disabled).
+func WithSlowlogTimeout(timeout time.Duration) Option {
+	return func(o *opt) error {
+		if timeout < 0 {
+			return fmt.Errorf("slowlog timeout must be >= 0, got %s", timeout)
+		}
+		o.slowlogTimeout = timeout
+
+		return nil
+	}
+}
+
+// WithScheduledTask runs the PHP script on a regular thread according to the cron expression,
+// for instance "*/5 * * * *" or "@daily". The script gets its arguments in $argv like a CLI script.
+func WithScheduledTask(name, expression, fileName string, options ...ScheduledTaskOption) Option {
+	return func(o *opt) error {
+		t, err := newScheduledTask(name, expression, fileName, options...)
+		if err != nil {
+			return err
+		}
+
+		o.scheduledTasks = append(o.scheduledTasks, t)
+
+		return nil
+	}
+}
+
+// WithCacheMaxSize sets the memory limit in bytes of the cache shared by all PHP threads,
+// the least recently used entries are evicted above it. Default: 32MB.
+func WithCacheMaxSize(size int64) Option {
+	return func(o *opt) error {
+		if size < 0 {
+			return fmt.Errorf("cache max size must be >= 0, got %d", size)
+		}
+		o.cacheMaxSize = size
+
+		return nil
+	}
+}
+
+// WithSSEHistorySize sets the number of events kept per channel of frankenphp_sse_publish(),
+// replayed to the clients reconnecting with a Last-Event-ID header. Default: 100, -1 disables the replay.
+func WithSSEHistorySize(size int) Option {
+	return func(o *opt) error {
+		if size < -1 {
+			return fmt.Errorf("SSE history size must be >= -1, got %d", size)
+		}
+		o.sseHistorySize = size
+
+		return nil
+	}
+}
+
+// WithMaxThreadMemory sets the default memory usage in bytes above which a PHP thread is restarted after a request (0 = unlimited).
+// Applies to regular and worker threads.
+func WithMaxThreadMemory(maxThreadMemory int64) Option {
+	return func(o *opt) error {
+		if maxThreadMemory < 0 {
+			return fmt.Errorf("max thread memory must be >= 0, got %d", maxThreadMemory)
+		}
+		o.maxThreadMemory = maxThreadMemory
+
+		return nil
+	}
+}
+
 // WithWorkerEnv sets environment variables for the worker
 func WithWorkerEnv(env map[string]string) WorkerOption {
 	return func(w *workerOpt) error {
@@ -210,6 +347,51 @@ func WithWorkerMaxThreads(num int) WorkerOption {
 	}
 }
 
+// WithWorkerMaxQueueLength sets the max number of requests that may be queued for this worker, overriding WithMaxQueueLength()
+func WithWorkerMaxQueueLength(maxQueueLength int) WorkerOption {
+	return func(w *workerOpt) error {
+		if maxQueueLength < 0 {
+			return fmt.Errorf("max queue length must be >= 0, got %d", maxQueueLength)
+		}
+		w.maxQueueLength = maxQueueLength
+
+		return nil
+	}
+}
+
+// WithWorkerMaxThreadMemory sets the memory usage in bytes above which a thread of this worker is restarted after a request.
+// Default: the global max thread memory.
+func WithWorkerMaxThreadMemory(maxThreadMemory int64) WorkerOption {
+	return func(w *workerOpt) error {
+		if maxThreadMemory < 0 {
+			return fmt.Errorf("max thread memory must be >= 0, got %d", maxThreadMemory)
+		}
+		w.maxThreadMemory = maxThreadMemory
+
+		return nil
+	}
+}
+
+// WithWorkerRestartStrategy sets how the threads of the worker are restarted on file changes or from the admin API.
+// Default: RestartStrategyAll.
+func WithWorkerRestartStrategy(strategy RestartStrategy) WorkerOption {
+	return func(w *workerOpt) error {
+		w.restartStrategy = strategy
+
+		return nil
+	}
+}
+
+// WithWorkerSubscriptions subscribes the worker to topics, the payloads passed to frankenphp_publish()
+// for these topics are sent to the callback of frankenphp_handle_request() of a thread of the worker.
+func WithWorkerSubscriptions(topics ...string) WorkerOption {
+	return func(w *workerOpt) error {
+		w.topics = append(w.topics, topics...)
+
+		return nil
+	}
+}
+
 // WithWorkerWatchMode sets directories to watch for file changes
 func WithWorkerWatchMode(watch []string) WorkerOption {
 	return func(w *workerOpt) error {
@@ -219,6 +401,32 @@ func WithWorkerWatchMode(watch []string) WorkerOption {
 	}
 }
 
+// WithWorkerBackground turns the worker into a background worker: the script runs its own loop instead of calling
+// frankenphp_handle_request() and is never matched to HTTP requests or messages. It is restarted when it exits,
+// with a backoff if it keeps failing, and must return when frankenphp_should_stop() returns true.
+func WithWorkerBackground() WorkerOption {
+	return func(w *workerOpt) error {
+		w.background = true
+
+		return nil
+	}
+}
+
+// WithWorkerWebSocket makes the worker accept WebSocket upgrades on the requests it handles: the connection is held by FrankenPHP,
+// and its events (open, message and close) are sent to the callback of frankenphp_handle_request() of a thread of the worker.
+// Scripts reply with frankenphp_ws_send() and frankenphp_ws_close().
+//
+// Browsers are only allowed to connect from the origin of the request, or from allowedOrigins
+// (e.g. "https://example.com", or "*" for any origin). Clients sending no Origin header are not browsers and are always allowed.
+func WithWorkerWebSocket(allowedOrigins ...string) WorkerOption {
+	return func(w *workerOpt) error {
+		w.websocket = true
+		w.websocketOrigins = append(w.websocketOrigins, allowedOrigins...)
+
+		return nil
+	}
+}
+
 // WithWorkerMatcher sets a request matcher for this worker
 // if the matcher returns true, the worker will be used to handle the request
 // if no request matcher is set, matching happens only by path (filename == root + request path)
@@ -229,6 +437,32 @@ func WithWorkerMatcher(matcherFunc func(*http.Request) bool) WorkerOption {
 	}
 }
 
+// WithWorkerRequestPriority sets a function computing the priority of the requests sent to this worker.
+// Queued requests are served by priority: each priority is a class that receives a share of the worker threads
+// proportional to its weight (defaults to the priority itself), so low priority requests cannot be starved.
+// Requests not sent over HTTP (e.g. through Workers.SendMessage) have priority 0.
+func WithWorkerRequestPriority(priorityFunc func(*http.Request) int) WorkerOption {
+	return func(w *workerOpt) error {
+		w.requestPriority = priorityFunc
+
+		return nil
+	}
+}
+
+// WithWorkerPriorityClass configures the weight and the max wait time of the requests with the given priority.
+// A zero maxWaitTime falls back to the global max wait time.
+func WithWorkerPriorityClass(priority, weight int, maxWaitTime time.Duration) WorkerOption {
+	return func(w *workerOpt) error {
+		if weight < 1 {
+			return fmt.Errorf("priority class weight must be >= 1, got %d", weight)
+		}
+
+		w.priorityClasses = append(w.priorityClasses, priorityClassOpt{priority, weight, maxWaitTime})
+
+		return nil
+	}
+}
+
 // WithWorkerServerScope scopes the worker to a server instance.
 // Only requests that are handled by the server instance will reach the worker.
 func WithWorkerServerScope(s *Server) WorkerOption {
@@ -313,6 +547,34 @@ func WithServerName(name string) ServerOption {
 	}
 }
 
+// WithServerMaxThreads limits the number of regular threads handling requests of the server at the same time (0 = unlimited),
+// so that one server cannot monopolize the threads shared with the other servers.
+// Requests exceeding the quota wait for a slot, up to max_wait_time. Worker threads are not affected.
+func WithServerMaxThreads(maxThreads int) ServerOption {
+	return func(s *Server) error {
+		if maxThreads < 0 {
+			return fmt.Errorf("server max_threads must be >= 0, got %d", maxThreads)
+		}
+		s.maxThreads = maxThreads
+
+		return nil
+	}
+}
+
+// WithServerMinThreads dedicates regular threads to the server (0 = none), they are started with the other threads and
+// never handle requests of another server. When the dedicated threads are busy, requests of the server overflow to
+// the shared threads, unless max_threads is equal to min_threads, in which case the server is fully isolated.
+func WithServerMinThreads(minThreads int) ServerOption {
+	return func(s *Server) error {
+		if minThreads < 0 {
+			return fmt.Errorf("server min_threads must be >= 0, got %d", minThreads)
+		}
+		s.minThreads = minThreads
+
+		return nil
+	}
+}
+
 // WithServerLogger sets the logger for the server.
 func WithServerLogger(l *slog.Logger) ServerOption {
 	return func(s *Server) error {
@@ -5,12 +5,12 @@ import (
 	"math/rand/v2"
 	"net/http/httptest"
 	"path/filepath"
-	"runtime"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/dunglas/frankenphp/internal/cpu"
 	"github.com/dunglas/frankenphp/internal/state"
 	"github.com/stretchr/testify/assert"
 )
@@ -323,7 +323,7 @@ func allPossibleTransitions(worker1Path string, worker2Path string) []func(*phpT
 }
 
 func TestCorrectThreadCalculation(t *testing.T) {
-	maxProcs := runtime.GOMAXPROCS(0) * 2
+	maxProcs := cpu.NumCPUs() * 2
 	oneWorkerThread := []workerOpt{{num: 1}}
 
 	// default values
@@ -353,8 +353,13 @@ func TestCorrectThreadCalculation(t *testing.T) {
 	testThreadCalculation(t, 2, 5, &opt{maxThreads: 5, workers: []workerOpt{{num: 1, maxThreads: 3}}})
 	testThreadCalculation(t, 3, 5, &opt{maxThreads: 5, workers: []workerOpt{{num: 1, maxThreads: 4}, {num: 1, maxThreads: 4}}})
 
+	// threads dedicated to servers are reserved like worker threads
+	testThreadCalculation(t, 4, 4, &opt{numThreads: 4, servers: []*Server{{minThreads: 2}}})
+	testThreadCalculation(t, 4, 10, &opt{maxThreads: 10, workers: oneWorkerThread, servers: []*Server{{minThreads: 2}}})
+
 	// not enough num threads
 	testThreadCalculationError(t, &opt{numThreads: 1, workers: oneWorkerThread})
+	testThreadCalculationError(t, &opt{numThreads: 2, servers: []*Server{{minThreads: 2}}})
 	testThreadCalculationError(t, &opt{numThreads: 1, maxThreads: 1, workers: oneWorkerThread})
 
 	// not enough max_threads
@@ -31,6 +31,9 @@ type phpThread struct {
 	// ts_free_thread.
 	forceKillMu sync.RWMutex
 	forceKill   C.force_kill_slot
+	// backtraceChan receives the call stack reported by the thread, see ThreadBacktrace()
+	backtraceMu   sync.Mutex
+	backtraceChan atomic.Pointer[chan []StackFrame]
 }
 
 // threadHandler defines how the callbacks from the C thread should be handled
@@ -93,6 +96,50 @@ func (thread *phpThread) reboot() bool {
 	return true
 }
 
+// memoryUsage returns the Zend memory used by the thread at the end of its last request
+func (thread *phpThread) memoryUsage() int64 {
+	return int64(C.frankenphp_get_thread_memory_usage(C.uintptr_t(thread.threadIndex)))
+}
+
+// drainAndReboot reboots the underlying C thread once it has finished its current request.
+// If force is true, the thread is force-killed when it has not yielded after the grace period.
+// Blocks until the new C thread is ready, returns false if the thread is shutting down.
+func (thread *phpThread) drainAndReboot(force bool) bool {
+	if !thread.state.RequestSafeStateChange(state.Rebooting) {
+		return false
+	}
+
+	thread.handlerMu.RLock()
+	thread.handler.drain()
+	thread.handlerMu.RUnlock()
+	close(thread.drainChan)
+
+	if !thread.state.WaitForStateWithTimeout(rebootGracePeriod, state.RebootReady) && force {
+		globalLogger.LogAttrs(
+			globalCtx,
+			slog.LevelWarn,
+			"force-killing thread on restart timeout",
+			slog.String("name", thread.name()),
+			slog.String("state", thread.state.Name()),
+			slog.String("timeout", rebootGracePeriod.String()),
+		)
+		thread.sendKillSignal()
+	}
+
+	thread.state.WaitFor(state.RebootReady)
+
+	// the C thread has exited, nothing reads the channel anymore
+	thread.drainChan = make(chan struct{})
+	thread.state.MarkAsWaiting(false)
+	if !C.frankenphp_new_php_thread(C.uintptr_t(thread.threadIndex)) {
+		panic("unable to create thread")
+	}
+
+	thread.state.WaitFor(state.Ready, state.Inactive, state.ShuttingDown)
+
+	return true
+}
+
 // force the underlying C thread to reboot. Will always reboot unless already shutting down or done.
 func (thread *phpThread) forceReboot() bool {
 	if !thread.state.RequestSafeStateChange(state.ForceRebooting) {
@@ -212,8 +259,8 @@ func (thread *phpThread) pinCString(s string) *C.char {
 	return thread.pinString(s + "\x00")
 }
 
-func (*phpThread) updateContext(isWorker bool) {
-	C.frankenphp_update_local_thread_context(C.bool(isWorker))
+func (*phpThread) updateContext(isWorker bool, isBackgroundWorker bool) {
+	C.frankenphp_update_local_thread_context(C.bool(isWorker), C.bool(isBackgroundWorker))
 }
 
 //export go_frankenphp_before_script_execution
@@ -242,6 +289,18 @@ func go_frankenphp_after_script_execution(threadIndex C.uintptr_t, exitStatus C.
 	thread.Unpin()
 }
 
+// go_frankenphp_should_stop reports whether the thread is asked to stop, see frankenphp_should_stop()
+//
+//export go_frankenphp_should_stop
+func go_frankenphp_should_stop(threadIndex C.uintptr_t) C.bool {
+	select {
+	case <-phpThreads[threadIndex].drainChan:
+		return true
+	default:
+		return false
+	}
+}
+
 //export go_frankenphp_store_force_kill_slot
 func go_frankenphp_store_force_kill_slot(threadIndex C.uintptr_t, slot C.force_kill_slot) {
 	thread := phpThreads[threadIndex]
@@ -0,0 +1,175 @@
+package frankenphp
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// priorityClass groups the queued requests of a worker that share the same priority
+type priorityClass struct {
+	priority    int
+	weight      int
+	maxWaitTime time.Duration
+	// credit used by the smooth weighted round-robin
+	currentWeight int
+	requests      []*queuedRequest
+}
+
+// queuedRequest is a request waiting for its turn in a priorityQueue
+type queuedRequest struct {
+	fc    *frankenPHPContext
+	class *priorityClass
+	// closed once the request is allowed to compete for a worker thread
+	turn chan struct{}
+}
+
+// priorityQueue orders the requests waiting for a worker thread
+// only the request at the head of the queue competes for a thread,
+// the next head is elected with a smooth weighted round-robin across classes
+// so that low priority requests cannot starve, but only get their share
+type priorityQueue struct {
+	mu              sync.Mutex
+	requestPriority func(*http.Request) int
+	classes         []*priorityClass // sorted by descending priority
+	classByPriority map[int]*priorityClass
+	head            *queuedRequest
+}
+
+// priorityClassOpt configures a priority class, see WithWorkerPriorityClass()
+type priorityClassOpt struct {
+	priority    int
+	weight      int
+	maxWaitTime time.Duration
+}
+
+func newPriorityQueue(requestPriority func(*http.Request) int, classOpts []priorityClassOpt) *priorityQueue {
+	q := &priorityQueue{
+		requestPriority: requestPriority,
+		classByPriority: make(map[int]*priorityClass, len(classOpts)),
+	}
+
+	for _, o := range classOpts {
+		c := q.getClass(o.priority)
+		if o.weight > 0 {
+			c.weight = o.weight
+		}
+		c.maxWaitTime = o.maxWaitTime
+	}
+
+	return q
+}
+
+// getClass returns the class for the given priority, creating it if needed
+// must be called with the lock held (or before the queue is shared)
+func (q *priorityQueue) getClass(priority int) *priorityClass {
+	if c, ok := q.classByPriority[priority]; ok {
+		return c
+	}
+
+	// by default, the share of a class is proportional to its priority
+	c := &priorityClass{priority: priority, weight: max(priority, 1)}
+	q.classByPriority[priority] = c
+	q.classes = append(q.classes, c)
+	slices.SortFunc(q.classes, func(a, b *priorityClass) int {
+		return b.priority - a.priority
+	})
+
+	return c
+}
+
+// push adds a request to the queue, it receives its turn immediately if nobody is waiting
+func (q *priorityQueue) push(fc *frankenPHPContext) *queuedRequest {
+	priority := 0
+	if fc.request != nil {
+		priority = q.requestPriority(fc.request)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	qr := &queuedRequest{
+		fc:    fc,
+		class: q.getClass(priority),
+		turn:  make(chan struct{}),
+	}
+	qr.class.requests = append(qr.class.requests, qr)
+
+	if q.head == nil {
+		q.electHead()
+	}
+
+	return qr
+}
+
+// remove takes a request out of the queue
+// returns false if the request already received its turn and must call release() instead
+func (q *priorityQueue) remove(qr *queuedRequest) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.head == qr {
+		return false
+	}
+
+	c := qr.class
+	if i := slices.Index(c.requests, qr); i >= 0 {
+		c.requests = slices.Delete(c.requests, i, i+1)
+	}
+
+	return true
+}
+
+// release gives the turn to the next request once the head has been dispatched or has timed out
+func (q *priorityQueue) release(qr *queuedRequest) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.head != qr {
+		return
+	}
+
+	q.head = nil
+	q.electHead()
+}
+
+// electHead picks the next head with a smooth weighted round-robin
+// ties are broken in favor of the higher priority
+// must be called with the lock held
+func (q *priorityQueue) electHead() {
+	var (
+		elected     *priorityClass
+		totalWeight int
+	)
+
+	for _, c := range q.classes {
+		if len(c.requests) == 0 {
+			continue
+		}
+
+		c.currentWeight += c.weight
+		totalWeight += c.weight
+		if elected == nil || c.currentWeight > elected.currentWeight {
+			elected = c
+		}
+	}
+
+	if elected == nil {
+		return
+	}
+
+	elected.currentWeight -= totalWeight
+	q.head = elected.requests[0]
+	elected.requests = slices.Delete(elected.requests, 0, 1)
+	close(q.head.turn)
+}
+
+// getMaxWaitTime returns the time a request of this class may wait, falling back to the global max_wait_time
+func (c *priorityClass) getMaxWaitTime() time.Duration {
+	if c.maxWaitTime > 0 {
+		return c.maxWaitTime
+	}
+
+	return time.Duration(maxWaitTime.Load())
+}
@@ -0,0 +1,71 @@
+package frankenphp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPriorityTestContext(path string) *frankenPHPContext {
+	return &frankenPHPContext{request: httptest.NewRequest(http.MethodGet, path, nil)}
+}
+
+func TestPriorityQueueServesByWeight(t *testing.T) {
+	q := newPriorityQueue(func(r *http.Request) int {
+		if r.URL.Path == "/api" {
+			return 3
+		}
+
+		return 0
+	}, nil)
+
+	// the first request gets the turn immediately
+	first := q.push(newPriorityTestContext("/report"))
+	requireTurn(t, first)
+
+	queued := make([]*queuedRequest, 0, 8)
+	for range 4 {
+		queued = append(queued, q.push(newPriorityTestContext("/report")))
+	}
+	for range 4 {
+		queued = append(queued, q.push(newPriorityTestContext("/api")))
+	}
+
+	var served []int
+	head := first
+	for range queued {
+		q.release(head)
+		head = q.head
+		requireTurn(t, head)
+		served = append(served, head.class.priority)
+	}
+
+	// priority 3 has weight 3, priority 0 has weight 1
+	assert.Equal(t, []int{3, 3, 0, 3, 3, 0, 0, 0}, served)
+}
+
+func TestPriorityQueueRemoveOnTimeout(t *testing.T) {
+	q := newPriorityQueue(func(*http.Request) int { return 1 }, []priorityClassOpt{{priority: 1, weight: 1}})
+
+	head := q.push(newPriorityTestContext("/"))
+	waiting := q.push(newPriorityTestContext("/"))
+
+	assert.False(t, q.remove(head), "the head must be released, not removed")
+	assert.True(t, q.remove(waiting))
+
+	q.release(head)
+	assert.Nil(t, q.head, "no request should be left in the queue")
+}
+
+func requireTurn(t *testing.T, qr *queuedRequest) {
+	t.Helper()
+
+	select {
+	case <-qr.turn:
+	default:
+		require.Fail(t, "request did not receive its turn")
+	}
+}
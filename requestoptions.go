@@ -205,11 +205,23 @@ func WithRequestBodyTimeout(timeout time.Duration) RequestOption {
 	}
 }
 
+// WithRequestTimeout sets the maximum wall clock time PHP may spend executing the request.
+// Once exceeded, the execution is interrupted (like with max_execution_time), a 504 status code is sent
+// if the headers have not been sent yet, and worker threads are restarted.
+// Zero (the default) disables it. The time spent waiting for a thread is not included, see WithMaxWaitTime().
+func WithRequestTimeout(timeout time.Duration) RequestOption {
+	return func(o *frankenPHPContext) error {
+		o.requestTimeout = timeout
+
+		return nil
+	}
+}
+
 // WithWorkerName sets the worker that should handle the request
 func WithWorkerName(name string) RequestOption {
 	return func(o *frankenPHPContext) error {
-		if name != "" {
-			o.worker = workersByName[name]
+		if w := workersByName[name]; w != nil && !w.background {
+			o.worker = w
 		}
 
 		return nil
@@ -0,0 +1,118 @@
+package frankenphp
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dunglas/frankenphp/internal/state"
+)
+
+const (
+	// RestartStrategyDefault uses the strategy configured for the worker
+	RestartStrategyDefault RestartStrategy = iota
+	// RestartStrategyAll drains all threads at once, then boots the worker script again
+	RestartStrategyAll
+	// RestartStrategyRolling restarts threads in small batches while the other threads keep serving requests
+	RestartStrategyRolling
+)
+
+type RestartStrategy int
+
+func (s RestartStrategy) String() string {
+	switch s {
+	case RestartStrategyAll:
+		return "all"
+	case RestartStrategyRolling:
+		return "rolling"
+	default:
+		return "default"
+	}
+}
+
+var (
+	// max time to wait for a restarted thread to reach frankenphp_handle_request before restarting the next batch
+	rollingRestartBootTimeout = 30 * time.Second
+	// interval at which restarted threads are checked during a rolling restart
+	rollingRestartPollInterval = 10 * time.Millisecond
+)
+
+// restartRolling restarts the threads in batches, waiting for each batch to be ready to handle requests.
+// If there is headroom in max_threads, an extra thread is booted first so capacity never drops.
+func (worker *worker) restartRolling(threads []*phpThread, force bool) {
+	// background workers do not serve requests, an extra thread would only run the script one more time
+	if !worker.background {
+		if extraThread := addRollingRestartThread(worker); extraThread != nil {
+			worker.waitForScript(extraThread)
+			defer removeRollingRestartThread(extraThread)
+		}
+	}
+
+	batchSize := max(1, len(threads)/4)
+	for batch := range slices.Chunk(threads, batchSize) {
+		var wg sync.WaitGroup
+		for _, thread := range batch {
+			wg.Go(func() {
+				if thread.drainAndReboot(force) {
+					worker.waitForScript(thread)
+				}
+			})
+		}
+		wg.Wait()
+	}
+}
+
+// addRollingRestartThread only holds scalingMu while picking and converting an inactive thread,
+// it returns nil if max_threads has been reached
+func addRollingRestartThread(worker *worker) *phpThread {
+	scalingMu.Lock()
+	defer scalingMu.Unlock()
+
+	if !mainThread.state.Is(state.Ready) {
+		return nil
+	}
+
+	thread, err := addWorkerThread(worker)
+	if err != nil {
+		return nil
+	}
+
+	return thread
+}
+
+func removeRollingRestartThread(thread *phpThread) {
+	scalingMu.Lock()
+	defer scalingMu.Unlock()
+
+	convertToInactiveThread(thread)
+}
+
+// waitForScript blocks until the thread waits in frankenphp_handle_request,
+// gives up after rollingRestartBootTimeout so a failing worker script cannot block the restart,
+// background workers have nothing to wait for
+func (worker *worker) waitForScript(thread *phpThread) {
+	if worker.background {
+		return
+	}
+
+	timeout := time.After(rollingRestartBootTimeout)
+	ticker := time.NewTicker(rollingRestartPollInterval)
+	defer ticker.Stop()
+
+	for !thread.state.IsInWaitingState() && thread.state.Is(state.Ready) {
+		select {
+		case <-ticker.C:
+		case <-timeout:
+			if globalLogger.Enabled(globalCtx, slog.LevelWarn) {
+				globalLogger.LogAttrs(globalCtx, slog.LevelWarn, "worker thread not ready after rolling restart timeout, continuing",
+					slog.String("worker", worker.name),
+					slog.Int("thread", thread.threadIndex),
+					slog.String("timeout", rollingRestartBootTimeout.String()),
+				)
+			}
+
+			return
+		}
+	}
+}
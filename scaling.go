@@ -2,20 +2,22 @@ package frankenphp
 
 import (
 	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
-	"github.com/dunglas/frankenphp/internal/cpu"
 	"github.com/dunglas/frankenphp/internal/state"
 )
 
 const (
 	// requests have to be stalled for at least this amount of time before scaling
+	// also the time to wait before asking the scaling policy again
 	minStallTime = 5 * time.Millisecond
-	// time to check for CPU usage before scaling a single thread
+	// time to check for CPU usage before scaling a single thread (CPU scaling policy)
 	cpuProbeTime = 120 * time.Millisecond
-	// do not scale over this amount of CPU usage
+	// do not scale over this amount of CPU usage (CPU scaling policy)
 	maxCpuUsageForScaling = 0.8
 	// downscale idle threads every x seconds
 	downScaleCheckTime = 5 * time.Second
@@ -29,9 +31,13 @@ var (
 	ErrMaxThreadsReached = errors.New("max amount of overall threads reached")
 
 	maxIdleTime       = defaultMaxIdleTime
+	scalingPolicy     = NewCPUScalingPolicy()
+	regularWaitTimes  = &waitTimeRecorder{}
 	scaleChan         chan *frankenPHPContext
 	autoScaledThreads = []*phpThread{}
-	scalingMu         = new(sync.RWMutex)
+	// max number of autoscaled threads (max_threads - num_threads), guarded by scalingMu
+	maxScaledThreads int
+	scalingMu        = new(sync.RWMutex)
 )
 
 func initAutoScaling(mainThread *phpMainThread) {
@@ -48,11 +54,11 @@ func initAutoScaling(mainThread *phpMainThread) {
 	mstate := mainThread.state
 
 	scalingMu.Lock()
-	maxScaledThreads := mainThread.maxThreads - mainThread.numThreads
+	maxScaledThreads = mainThread.maxThreads - mainThread.numThreads
 	autoScaledThreads = make([]*phpThread, 0, maxScaledThreads)
 	scalingMu.Unlock()
 
-	go startUpscalingThreads(maxScaledThreads, scaleChan, done, mstate)
+	go startUpscalingThreads(scaleChan, done, mstate)
 	go startDownScalingThreads(done)
 }
 
@@ -79,12 +85,7 @@ func addWorkerThread(worker *worker) (*phpThread, error) {
 }
 
 // scaleWorkerThread adds a worker PHP thread automatically
-func scaleWorkerThread(worker *worker, done chan struct{}, mstate *state.ThreadState) {
-	// probe CPU usage before acquiring the lock (avoids holding lock during 120ms sleep)
-	if !cpu.ProbeCPUs(cpuProbeTime, maxCpuUsageForScaling, done) {
-		return
-	}
-
+func scaleWorkerThread(worker *worker, mstate *state.ThreadState) {
 	scalingMu.Lock()
 	defer scalingMu.Unlock()
 
@@ -109,12 +110,7 @@ func scaleWorkerThread(worker *worker, done chan struct{}, mstate *state.ThreadS
 }
 
 // scaleRegularThread adds a regular PHP thread automatically
-func scaleRegularThread(done chan struct{}, mstate *state.ThreadState) {
-	// probe CPU usage before acquiring the lock (avoids holding lock during 120ms sleep)
-	if !cpu.ProbeCPUs(cpuProbeTime, maxCpuUsageForScaling, done) {
-		return
-	}
-
+func scaleRegularThread(mstate *state.ThreadState) {
 	scalingMu.Lock()
 	defer scalingMu.Unlock()
 
@@ -138,12 +134,12 @@ func scaleRegularThread(done chan struct{}, mstate *state.ThreadState) {
 	}
 }
 
-func startUpscalingThreads(maxScaledThreads int, scale chan *frankenPHPContext, done chan struct{}, mstate *state.ThreadState) {
+func startUpscalingThreads(scale chan *frankenPHPContext, done chan struct{}, mstate *state.ThreadState) {
 	for {
 		scalingMu.Lock()
-		scaledThreadCount := len(autoScaledThreads)
+		atLimit := len(autoScaledThreads) >= maxScaledThreads
 		scalingMu.Unlock()
-		if scaledThreadCount >= maxScaledThreads {
+		if atLimit {
 			// we have reached max_threads, check again later
 			select {
 			case <-done:
@@ -155,21 +151,19 @@ func startUpscalingThreads(maxScaledThreads int, scale chan *frankenPHPContext,
 
 		select {
 		case fc := <-scale:
-			timeSinceStalled := time.Since(fc.startedAt)
-
-			// if the request has not been stalled long enough, wait and repeat
-			if timeSinceStalled < minStallTime {
+			// ask the policy before acquiring the lock (the policy may block, e.g. to probe the CPU usage)
+			// if the policy declines to scale, wait and repeat
+			if !scalingPolicy.ShouldScaleUp(newQueueStats(fc, done)) {
 				select {
 				case <-done:
 					return
-				case <-time.After(minStallTime - timeSinceStalled):
+				case <-time.After(minStallTime):
 					continue
 				}
 			}
 
-			// if the request has been stalled long enough, scale
 			if fc.worker == nil {
-				scaleRegularThread(done, mstate)
+				scaleRegularThread(mstate)
 				continue
 			}
 
@@ -182,7 +176,7 @@ func startUpscalingThreads(maxScaledThreads int, scale chan *frankenPHPContext,
 				continue
 			}
 
-			scaleWorkerThread(fc.worker, done, mstate)
+			scaleWorkerThread(fc.worker, mstate)
 		case <-done:
 			return
 		}
@@ -219,8 +213,8 @@ func deactivateThreads() {
 			continue
 		}
 
-		// convert threads to inactive if they have been idle for too long
-		if thread.state.Is(state.Ready) && waitTime > maxIdleTime.Milliseconds() {
+		// convert threads to inactive if the scaling policy considers them idle for too long
+		if thread.state.Is(state.Ready) && scalingPolicy.ShouldScaleDown(newThreadStats(thread, waitTime)) {
 			convertToInactiveThread(thread)
 			stoppedThreadCount++
 			autoScaledThreads = append(autoScaledThreads[:i], autoScaledThreads[i+1:]...)
@@ -244,3 +238,143 @@ func deactivateThreads() {
 		// }
 	}
 }
+
+// SetMaxThreads changes max_threads at runtime.
+// It cannot exceed the max_threads set when FrankenPHP was started, since threads are preallocated.
+// If lowered, the autoscaled threads over the new limit are deactivated once they have finished their current request.
+func SetMaxThreads(maxThreads int) error {
+	scalingMu.Lock()
+	defer scalingMu.Unlock()
+
+	if mainThread == nil || !mainThread.state.Is(state.Ready) {
+		return ErrNotStarted
+	}
+
+	if maxThreads < mainThread.numThreads {
+		return fmt.Errorf("max_threads (%d) must be greater or equal to num_threads (%d)", maxThreads, mainThread.numThreads)
+	}
+
+	if maxThreads > len(phpThreads) {
+		return fmt.Errorf("max_threads (%d) cannot be raised above %d at runtime, reload the configuration instead", maxThreads, len(phpThreads))
+	}
+
+	mainThread.maxThreads = maxThreads
+	maxScaledThreads = maxThreads - mainThread.numThreads
+
+	for len(autoScaledThreads) > maxScaledThreads {
+		thread := autoScaledThreads[len(autoScaledThreads)-1]
+		autoScaledThreads = autoScaledThreads[:len(autoScaledThreads)-1]
+		convertToInactiveThread(thread)
+	}
+
+	if globalLogger.Enabled(globalCtx, slog.LevelInfo) {
+		globalLogger.LogAttrs(globalCtx, slog.LevelInfo, "max_threads changed", slog.Int("max_threads", maxThreads), slog.Int("num_threads", mainThread.numThreads))
+	}
+
+	return nil
+}
+
+// SetWorkerThreads changes the number of threads started for the worker with the given name (its "num") at runtime.
+// Threads are taken from or given back to the pool of inactive threads, threads added by autoscaling are not affected.
+func SetWorkerThreads(name string, num int) error {
+	w, ok := workersByName[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrWorkerNotFound, name)
+	}
+
+	if num < 1 {
+		return fmt.Errorf("worker num must be greater than 0, got %d", num)
+	}
+
+	if w.maxThreads > 0 && num > w.maxThreads {
+		return fmt.Errorf("worker num (%d) cannot be greater than worker max_threads (%d)", num, w.maxThreads)
+	}
+
+	scalingMu.Lock()
+	defer scalingMu.Unlock()
+
+	if mainThread == nil || !mainThread.state.Is(state.Ready) {
+		return ErrNotStarted
+	}
+
+	w.threadMutex.RLock()
+	baseThreads := make([]*phpThread, 0, len(w.threads))
+	for _, thread := range w.threads {
+		if !slices.Contains(autoScaledThreads, thread) {
+			baseThreads = append(baseThreads, thread)
+		}
+	}
+	w.threadMutex.RUnlock()
+
+	// the threads of the worker count towards num_threads, the autoscaled threads fill the rest of max_threads
+	numThreads := mainThread.numThreads + num - len(baseThreads)
+	if numThreads > mainThread.maxThreads {
+		return fmt.Errorf("worker num (%d) would raise num_threads (%d) above max_threads (%d)", num, numThreads, mainThread.maxThreads)
+	}
+
+	// make room for the new threads first, the autoscaled threads over the new limit are deactivated
+	mainThread.numThreads = numThreads
+	maxScaledThreads = mainThread.maxThreads - numThreads
+
+	for len(autoScaledThreads) > maxScaledThreads {
+		thread := autoScaledThreads[len(autoScaledThreads)-1]
+		autoScaledThreads = autoScaledThreads[:len(autoScaledThreads)-1]
+		convertToInactiveThread(thread)
+	}
+
+	for i := len(baseThreads); i < num; i++ {
+		if _, err := addWorkerThread(w); err != nil {
+			// only count the threads actually added
+			w.num = i
+			mainThread.numThreads -= num - i
+			maxScaledThreads = mainThread.maxThreads - mainThread.numThreads
+
+			return err
+		}
+	}
+
+	for i := len(baseThreads) - 1; i >= num; i-- {
+		convertToInactiveThread(baseThreads[i])
+	}
+
+	w.num = num
+
+	if globalLogger.Enabled(globalCtx, slog.LevelInfo) {
+		globalLogger.LogAttrs(globalCtx, slog.LevelInfo, "worker threads changed", slog.String("worker", w.name), slog.Int("num", num), slog.Int("previous_num", len(baseThreads)))
+	}
+
+	return nil
+}
+
+// newQueueStats collects the stats of the queue a stalled request is waiting in
+func newQueueStats(fc *frankenPHPContext, done chan struct{}) QueueStats {
+	if fc.worker == nil {
+		return QueueStats{
+			QueuedRequests: int(queuedRegularThreads.Load()),
+			StallTime:      time.Since(fc.startedAt),
+			P95WaitTime:    regularWaitTimes.percentile(0.95),
+			Done:           done,
+		}
+	}
+
+	return QueueStats{
+		Worker:         fc.worker.name,
+		QueuedRequests: int(fc.worker.queuedRequests.Load()),
+		StallTime:      time.Since(fc.startedAt),
+		P95WaitTime:    fc.worker.waitTimes.percentile(0.95),
+		Done:           done,
+	}
+}
+
+// newThreadStats collects the stats of an idle autoscaled thread
+func newThreadStats(thread *phpThread, waitTime int64) ThreadStats {
+	stats := ThreadStats{IdleTime: time.Duration(waitTime) * time.Millisecond}
+
+	thread.handlerMu.RLock()
+	if handler, ok := thread.handler.(*workerThread); ok {
+		stats.Worker = handler.worker.name
+	}
+	thread.handlerMu.RUnlock()
+
+	return stats
+}
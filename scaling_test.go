@@ -20,7 +20,7 @@ func TestScaleARegularThreadUpAndDown(t *testing.T) {
 	autoScaledThread := phpThreads[1]
 
 	// scale up
-	scaleRegularThread(mainThread.done, mainThread.state)
+	scaleRegularThread(mainThread.state)
 	assert.Equal(t, state.Ready, autoScaledThread.state.Get())
 	assert.IsType(t, &regularThread{}, autoScaledThread.handler)
 
@@ -48,7 +48,7 @@ func TestScaleAWorkerThreadUpAndDown(t *testing.T) {
 	autoScaledThread := phpThreads[2]
 
 	// scale up
-	scaleWorkerThread(globalWorkersByPath[workerPath], mainThread.done, mainThread.state)
+	scaleWorkerThread(globalWorkersByPath[workerPath], mainThread.state)
 	assert.Equal(t, state.Ready, autoScaledThread.state.Get())
 
 	// on down-scale, the thread will be marked as inactive
@@ -69,7 +69,7 @@ func TestMaxIdleTimePreventsEarlyDeactivation(t *testing.T) {
 	autoScaledThread := phpThreads[1]
 
 	// scale up
-	scaleRegularThread(mainThread.done, mainThread.state)
+	scaleRegularThread(mainThread.state)
 	assert.Equal(t, state.Ready, autoScaledThread.state.Get())
 
 	// set wait time to 30 minutes (less than 1 hour max idle time)
@@ -83,6 +83,58 @@ func TestMaxIdleTimePreventsEarlyDeactivation(t *testing.T) {
 	assert.IsType(t, &inactiveThread{}, autoScaledThread.handler, "thread should be deactivated after exceeding max idle time")
 }
 
+func TestSetWorkerThreadsAtRuntime(t *testing.T) {
+	t.Cleanup(Shutdown)
+
+	workerName := "worker1"
+	workerPath := filepath.Join(testDataPath, "transition-worker-1.php")
+	assert.NoError(t, Init(
+		WithNumThreads(2),
+		WithMaxThreads(4),
+		WithWorkers(workerName, workerPath, 1),
+	))
+	worker := workersByName[workerName]
+
+	assert.NoError(t, SetWorkerThreads(workerName, 3))
+	assert.Equal(t, 3, worker.countThreads())
+	assert.Equal(t, 4, mainThread.numThreads)
+	assert.Equal(t, 0, maxScaledThreads)
+	assert.Error(t, SetMaxThreads(3), "max_threads cannot be lower than the new num_threads")
+	assert.Error(t, SetWorkerThreads(workerName, 4), "num_threads cannot exceed max_threads")
+
+	assert.NoError(t, SetWorkerThreads(workerName, 1))
+	assert.Equal(t, 1, worker.countThreads())
+	assert.Equal(t, 2, mainThread.numThreads)
+	assert.Equal(t, 2, maxScaledThreads)
+
+	assert.ErrorIs(t, SetWorkerThreads("unknown", 1), ErrWorkerNotFound)
+	assert.Error(t, SetWorkerThreads(workerName, 0))
+}
+
+func TestSetMaxThreadsAtRuntime(t *testing.T) {
+	assert.ErrorIs(t, SetMaxThreads(2), ErrNotStarted)
+
+	t.Cleanup(Shutdown)
+
+	assert.NoError(t, Init(
+		WithNumThreads(1),
+		WithMaxThreads(3),
+	))
+
+	scaleRegularThread(mainThread.state)
+	scaleRegularThread(mainThread.state)
+	assert.Len(t, autoScaledThreads, 2)
+
+	// lowering max_threads deactivates the autoscaled threads over the limit
+	assert.NoError(t, SetMaxThreads(2))
+	assert.Len(t, autoScaledThreads, 1)
+	assert.IsType(t, &inactiveThread{}, phpThreads[2].handler)
+
+	assert.Error(t, SetMaxThreads(0), "max_threads cannot be lower than num_threads")
+	assert.Error(t, SetMaxThreads(4), "max_threads cannot exceed the preallocated threads")
+	assert.NoError(t, SetMaxThreads(3))
+}
+
 func setLongWaitTime(t *testing.T, thread *phpThread) {
 	t.Helper()
 
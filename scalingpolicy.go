@@ -0,0 +1,130 @@
+package frankenphp
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dunglas/frankenphp/internal/cpu"
+)
+
+// number of recent wait times kept to compute percentiles
+const waitTimeSampleSize = 128
+
+// QueueStats describes the queue of a stalled request, it is passed to ScalingPolicy.ShouldScaleUp()
+type QueueStats struct {
+	// Worker is the name of the worker the request is queued for, empty for regular threads
+	Worker string
+	// QueuedRequests is the number of requests currently waiting in the queue
+	QueuedRequests int
+	// StallTime is the time the stalled request has been waiting for a thread
+	StallTime time.Duration
+	// P95WaitTime is the 95th percentile of the time the recently queued requests waited for a thread
+	P95WaitTime time.Duration
+	// Done is closed when FrankenPHP shuts down, blocking policies must return as soon as it is
+	Done <-chan struct{}
+}
+
+// ThreadStats describes an autoscaled thread, it is passed to ScalingPolicy.ShouldScaleDown()
+type ThreadStats struct {
+	// Worker is the name of the worker the thread is assigned to, empty for regular threads
+	Worker string
+	// IdleTime is the time the thread has been waiting for a request
+	IdleTime time.Duration
+}
+
+// ScalingPolicy decides when threads are added or removed at runtime (between num_threads and max_threads).
+// ShouldScaleUp is called when a request is stalled waiting for a thread,
+// ShouldScaleDown is called periodically for every idle autoscaled thread.
+// Implementations may block (e.g. to probe resources) and must be safe for concurrent use.
+type ScalingPolicy interface {
+	ShouldScaleUp(stats QueueStats) bool
+	ShouldScaleDown(stats ThreadStats) bool
+}
+
+// idlePolicy deactivates threads that have been idle for longer than max_idle_time
+type idlePolicy struct{}
+
+func (idlePolicy) ShouldScaleDown(stats ThreadStats) bool {
+	return stats.IdleTime > maxIdleTime
+}
+
+type cpuScalingPolicy struct {
+	idlePolicy
+}
+
+// NewCPUScalingPolicy returns the default scaling policy.
+// A thread is added once a request has been stalled for a few milliseconds and if the CPU usage is below 80%.
+func NewCPUScalingPolicy() ScalingPolicy {
+	return cpuScalingPolicy{}
+}
+
+func (cpuScalingPolicy) ShouldScaleUp(stats QueueStats) bool {
+	if stats.StallTime < minStallTime {
+		return false
+	}
+
+	return cpu.ProbeCPUs(cpuProbeTime, maxCpuUsageForScaling, stats.Done)
+}
+
+type queueDepthScalingPolicy struct {
+	idlePolicy
+	minQueuedRequests int
+}
+
+// NewQueueDepthScalingPolicy returns a scaling policy adding a thread once at least minQueuedRequests requests are queued.
+func NewQueueDepthScalingPolicy(minQueuedRequests int) ScalingPolicy {
+	return queueDepthScalingPolicy{minQueuedRequests: max(minQueuedRequests, 1)}
+}
+
+func (p queueDepthScalingPolicy) ShouldScaleUp(stats QueueStats) bool {
+	return stats.QueuedRequests >= p.minQueuedRequests
+}
+
+type latencyScalingPolicy struct {
+	idlePolicy
+	target time.Duration
+}
+
+// NewLatencyScalingPolicy returns a scaling policy adding a thread once the 95th percentile
+// of the time requests wait for a thread (or the wait time of the stalled request) exceeds target.
+func NewLatencyScalingPolicy(target time.Duration) ScalingPolicy {
+	return latencyScalingPolicy{target: target}
+}
+
+func (p latencyScalingPolicy) ShouldScaleUp(stats QueueStats) bool {
+	return stats.StallTime >= p.target || stats.P95WaitTime >= p.target
+}
+
+// waitTimeRecorder keeps the most recent wait times of a queue (or request durations) to compute percentiles
+type waitTimeRecorder struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func (r *waitTimeRecorder) record(waitTime time.Duration) {
+	r.mu.Lock()
+	if len(r.samples) < waitTimeSampleSize {
+		r.samples = append(r.samples, waitTime)
+	} else {
+		r.samples[r.next] = waitTime
+		r.next = (r.next + 1) % waitTimeSampleSize
+	}
+	r.mu.Unlock()
+}
+
+// percentile returns the p-th percentile (0 < p <= 1) of the recorded wait times
+func (r *waitTimeRecorder) percentile(p float64) time.Duration {
+	r.mu.Lock()
+	sorted := slices.Clone(r.samples)
+	r.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	slices.Sort(sorted)
+
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
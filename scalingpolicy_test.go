@@ -0,0 +1,41 @@
+package frankenphp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueDepthScalingPolicy(t *testing.T) {
+	policy := NewQueueDepthScalingPolicy(3)
+
+	assert.False(t, policy.ShouldScaleUp(QueueStats{QueuedRequests: 2}))
+	assert.True(t, policy.ShouldScaleUp(QueueStats{QueuedRequests: 3}))
+	assert.False(t, policy.ShouldScaleDown(ThreadStats{IdleTime: maxIdleTime}))
+	assert.True(t, policy.ShouldScaleDown(ThreadStats{IdleTime: maxIdleTime + time.Second}))
+}
+
+func TestLatencyScalingPolicy(t *testing.T) {
+	policy := NewLatencyScalingPolicy(100 * time.Millisecond)
+
+	assert.False(t, policy.ShouldScaleUp(QueueStats{StallTime: 10 * time.Millisecond, P95WaitTime: 50 * time.Millisecond}))
+	assert.True(t, policy.ShouldScaleUp(QueueStats{StallTime: 10 * time.Millisecond, P95WaitTime: 150 * time.Millisecond}))
+	assert.True(t, policy.ShouldScaleUp(QueueStats{StallTime: 150 * time.Millisecond}))
+}
+
+func TestWaitTimeRecorderPercentile(t *testing.T) {
+	r := &waitTimeRecorder{}
+	assert.Equal(t, time.Duration(0), r.percentile(0.95))
+
+	for i := 1; i <= 100; i++ {
+		r.record(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, 95*time.Millisecond, r.percentile(0.95))
+
+	// only the most recent samples are kept
+	for range waitTimeSampleSize {
+		r.record(time.Second)
+	}
+	assert.Equal(t, time.Second, r.percentile(0.5))
+}
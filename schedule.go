@@ -0,0 +1,351 @@
+package frankenphp
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dunglas/frankenphp/internal/fastabs"
+)
+
+const (
+	// OverlapSkip skips a run if the previous one is still running
+	OverlapSkip OverlapPolicy = iota
+	// OverlapAllow starts a run even if the previous one is still running
+	OverlapAllow
+	// OverlapQueue delays a run until the previous one is finished, at most one run is pending
+	OverlapQueue
+)
+
+// OverlapPolicy defines what happens when a scheduled task is due while its previous run is still running.
+type OverlapPolicy int
+
+func (p OverlapPolicy) String() string {
+	switch p {
+	case OverlapAllow:
+		return "allow"
+	case OverlapQueue:
+		return "queue"
+	default:
+		return "skip"
+	}
+}
+
+// ScheduledTaskOption instances allow configuring a scheduled task.
+type ScheduledTaskOption func(*scheduledTask) error
+
+// clock abstracts the time for the scheduler, it is replaced in tests
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+var scheduleClock clock = realClock{}
+
+// scheduledTask is a PHP script executed on a regular thread according to a cron expression
+type scheduledTask struct {
+	name       string
+	expression string
+	schedule   *cronSchedule
+	fileName   string
+	args       []string
+	env        PreparedEnv
+	timeout    time.Duration
+	overlap    OverlapPolicy
+
+	// held while the task is running, unless the overlap policy is OverlapAllow
+	runMu sync.Mutex
+	// whether a run is waiting for the previous one to finish (OverlapQueue)
+	pending atomic.Bool
+	// running runs, waited for on shutdown
+	runs sync.WaitGroup
+}
+
+func newScheduledTask(name, expression, fileName string, options ...ScheduledTaskOption) (*scheduledTask, error) {
+	schedule, err := parseCron(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &scheduledTask{
+		name:       name,
+		expression: expression,
+		schedule:   schedule,
+		fileName:   fileName,
+		env:        PrepareEnv(nil),
+	}
+
+	for _, o := range options {
+		if err := o(t); err != nil {
+			return nil, err
+		}
+	}
+
+	absFileName, err := fastabs.FastAbs(filepath.FromSlash(fileName))
+	if err != nil {
+		return nil, fmt.Errorf("scheduled task filename is invalid %q: %w", fileName, err)
+	}
+
+	if _, err := os.Stat(absFileName); err != nil {
+		return nil, fmt.Errorf("scheduled task file not found %q: %w", absFileName, err)
+	}
+	t.fileName = absFileName
+
+	if t.name == "" {
+		t.name = absFileName
+	}
+
+	return t, nil
+}
+
+// WithScheduledTaskArgs sets the arguments passed to the script in $argv, after the script name
+func WithScheduledTaskArgs(args ...string) ScheduledTaskOption {
+	return func(t *scheduledTask) error {
+		t.args = args
+
+		return nil
+	}
+}
+
+// WithScheduledTaskEnv sets extra environment variables for the script
+func WithScheduledTaskEnv(env map[string]string) ScheduledTaskOption {
+	return func(t *scheduledTask) error {
+		t.env = PrepareEnv(env)
+
+		return nil
+	}
+}
+
+// WithScheduledTaskTimeout sets the maximum wall clock time a run may take before being interrupted (0 = unlimited)
+func WithScheduledTaskTimeout(timeout time.Duration) ScheduledTaskOption {
+	return func(t *scheduledTask) error {
+		if timeout < 0 {
+			return fmt.Errorf("scheduled task timeout must be >= 0, got %s", timeout)
+		}
+		t.timeout = timeout
+
+		return nil
+	}
+}
+
+// WithScheduledTaskOverlapPolicy sets what happens when the task is due while its previous run is still running.
+// Default: OverlapSkip.
+func WithScheduledTaskOverlapPolicy(policy OverlapPolicy) ScheduledTaskOption {
+	return func(t *scheduledTask) error {
+		t.overlap = policy
+
+		return nil
+	}
+}
+
+var (
+	scheduledTasks []*scheduledTask
+	// closed to stop the scheduler, nil if it is not running
+	schedulerDone chan struct{}
+	schedulerWG   sync.WaitGroup
+)
+
+func initScheduler(tasks []*scheduledTask) {
+	scheduledTasks = tasks
+	if len(tasks) == 0 {
+		return
+	}
+
+	schedulerDone = make(chan struct{})
+	for _, t := range tasks {
+		schedulerWG.Go(func() {
+			t.loop(schedulerDone)
+		})
+	}
+}
+
+// drainScheduler stops scheduling new runs and waits for the running ones, it must be called before draining the threads
+// Like when draining the threads, the runs still going after the grace period are force-killed.
+func drainScheduler() {
+	if schedulerDone == nil {
+		return
+	}
+
+	close(schedulerDone)
+	schedulerWG.Wait()
+
+	runsDone := make(chan struct{})
+	go func() {
+		for _, t := range scheduledTasks {
+			t.runs.Wait()
+		}
+		close(runsDone)
+	}()
+
+	for waiting := true; waiting; {
+		select {
+		case <-runsDone:
+			waiting = false
+		case <-time.After(shutDownGracePeriod):
+			globalLogger.LogAttrs(
+				globalCtx,
+				slog.LevelWarn,
+				"force-killing scheduled tasks on shutdown timeout",
+				slog.String("timeout", shutDownGracePeriod.String()),
+			)
+			killScheduledTasks()
+		}
+	}
+
+	schedulerDone = nil
+	scheduledTasks = nil
+}
+
+// killScheduledTasks sends the kill signal to the threads running a scheduled task
+func killScheduledTasks() {
+	for _, thread := range phpThreads {
+		if thread.runsScheduledTask() {
+			thread.sendKillSignal()
+		}
+	}
+}
+
+func (thread *phpThread) runsScheduledTask() bool {
+	thread.handlerMu.RLock()
+	handler, ok := thread.handler.(*regularThread)
+	thread.handlerMu.RUnlock()
+	if !ok {
+		return false
+	}
+
+	thread.contextMu.RLock()
+	defer thread.contextMu.RUnlock()
+
+	return handler.fc != nil && handler.fc.isScheduledTask
+}
+
+func (t *scheduledTask) loop(done chan struct{}) {
+	for {
+		now := scheduleClock.Now()
+		next := t.schedule.next(now)
+		if next.IsZero() {
+			if globalLogger.Enabled(globalCtx, slog.LevelWarn) {
+				globalLogger.LogAttrs(globalCtx, slog.LevelWarn, "scheduled task will never run", slog.String("task", t.name), slog.String("schedule", t.expression))
+			}
+
+			return
+		}
+
+		select {
+		case <-done:
+			return
+		case <-scheduleClock.After(next.Sub(now)):
+		}
+
+		t.trigger()
+	}
+}
+
+// trigger starts a run according to the overlap policy
+func (t *scheduledTask) trigger() {
+	switch t.overlap {
+	case OverlapAllow:
+	case OverlapQueue:
+		if !t.pending.CompareAndSwap(false, true) {
+			t.logSkipped()
+
+			return
+		}
+	default:
+		if !t.runMu.TryLock() {
+			t.logSkipped()
+
+			return
+		}
+	}
+
+	t.runs.Go(func() {
+		switch t.overlap {
+		case OverlapAllow:
+		case OverlapQueue:
+			t.runMu.Lock()
+			t.pending.Store(false)
+			defer t.runMu.Unlock()
+		default:
+			defer t.runMu.Unlock()
+		}
+
+		t.run()
+	})
+}
+
+func (t *scheduledTask) logSkipped() {
+	if globalLogger.Enabled(globalCtx, slog.LevelWarn) {
+		globalLogger.LogAttrs(globalCtx, slog.LevelWarn, "scheduled task is still running, skipping this run", slog.String("task", t.name), slog.String("overlap", t.overlap.String()))
+	}
+}
+
+// run executes the script on a regular thread and waits for it to finish
+func (t *scheduledTask) run() {
+	startedAt := scheduleClock.Now()
+	if globalLogger.Enabled(globalCtx, slog.LevelDebug) {
+		globalLogger.LogAttrs(globalCtx, slog.LevelDebug, "starting scheduled task", slog.String("task", t.name), slog.String("script", t.fileName))
+	}
+
+	fc, err := t.newContext()
+	if err == nil {
+		err = handleRequestWithRegularPHPThreads(fc)
+	}
+	duration := scheduleClock.Now().Sub(startedAt)
+
+	if err != nil {
+		metrics.StopScheduledTask(t.name, -1, startedAt, duration)
+		if globalLogger.Enabled(globalCtx, slog.LevelError) {
+			globalLogger.LogAttrs(globalCtx, slog.LevelError, "unable to start the scheduled task", slog.String("task", t.name), slog.Any("error", err))
+		}
+
+		return
+	}
+
+	metrics.StopScheduledTask(t.name, fc.exitStatus, startedAt, duration)
+
+	level := slog.LevelInfo
+	if fc.exitStatus != 0 || fc.timedOut.Load() {
+		level = slog.LevelError
+	}
+	if globalLogger.Enabled(globalCtx, level) {
+		globalLogger.LogAttrs(globalCtx, level, "scheduled task finished", slog.String("task", t.name), slog.Int("exit_status", fc.exitStatus), slog.Duration("duration", duration), slog.Bool("timed_out", fc.timedOut.Load()))
+	}
+}
+
+// newContext creates the context of a run, the script is executed like a CLI script with $argv
+func (t *scheduledTask) newContext() (*frankenPHPContext, error) {
+	scriptName := "/" + filepath.Base(t.fileName)
+
+	r, err := http.NewRequestWithContext(globalCtx, http.MethodGet, scriptName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &frankenPHPContext{
+		ctx:             r.Context(),
+		done:            make(chan any),
+		startedAt:       time.Now(),
+		server:          fallbackServer,
+		logger:          fallbackServer.logger,
+		request:         r,
+		env:             t.env,
+		requestTimeout:  t.timeout,
+		isScheduledTask: true,
+		args:            append([]string{t.fileName}, t.args...),
+		documentRoot:    filepath.Dir(t.fileName),
+		scriptName:      scriptName,
+		scriptFilename:  t.fileName,
+		requestURI:      scriptName,
+	}, nil
+}
@@ -0,0 +1,162 @@
+package frankenphp
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), c: ch})
+
+	return ch
+}
+
+func (c *fakeClock) pendingTimers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers)
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	timers := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			timers = append(timers, timer)
+
+			continue
+		}
+
+		timer.c <- c.now
+	}
+	c.timers = timers
+}
+
+func TestScheduledTask(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, time.January, 15, 10, 30, 20, 0, time.UTC)}
+	scheduleClock = clock
+	t.Cleanup(func() {
+		scheduleClock = realClock{}
+	})
+
+	file := filepath.Join(t.TempDir(), "scheduled-task.log")
+	m := NewPrometheusMetrics(prometheus.NewRegistry())
+
+	require.NoError(t, Init(
+		WithNumThreads(2),
+		WithMetrics(m),
+		WithScheduledTask("test", "*/5 * * * *", filepath.Join(testDataPath, "scheduled-task.php"),
+			WithScheduledTaskArgs("hello", "3"),
+			WithScheduledTaskEnv(map[string]string{"SCHEDULED_TASK_FILE": file}),
+		),
+	))
+	t.Cleanup(Shutdown)
+
+	require.Eventually(t, func() bool { return clock.pendingTimers() == 1 }, time.Second, time.Millisecond)
+
+	// not due yet
+	clock.advance(time.Minute)
+	assert.Equal(t, 1, clock.pendingTimers())
+
+	clock.advance(4 * time.Minute)
+	require.Eventually(t, func() bool {
+		b, _ := os.ReadFile(file)
+
+		return string(b) == "hello 3\n"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(m.taskLastStatus.WithLabelValues("test")) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.InDelta(t, float64(clock.Now().Unix()), testutil.ToFloat64(m.taskLastRun.WithLabelValues("test")), 1e-3)
+}
+
+func TestScheduledTaskSkipsOverlappingRuns(t *testing.T) {
+	var buf bytes.Buffer
+	globalLogger = slog.New(slog.NewTextHandler(&buf, nil))
+	t.Cleanup(func() {
+		globalLogger = slog.Default()
+	})
+
+	task := &scheduledTask{name: "test"}
+	task.runMu.Lock()
+	task.trigger()
+
+	assert.Contains(t, buf.String(), "scheduled task is still running, skipping this run")
+}
+
+func TestShutdownForceKillsScheduledTasks(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, time.January, 15, 10, 30, 0, 0, time.UTC)}
+	scheduleClock = clock
+	previousGracePeriod := shutDownGracePeriod
+	shutDownGracePeriod = 100 * time.Millisecond
+	t.Cleanup(func() {
+		scheduleClock = realClock{}
+		shutDownGracePeriod = previousGracePeriod
+	})
+
+	script := filepath.Join(t.TempDir(), "endless-task.php")
+	require.NoError(t, os.WriteFile(script, []byte("<?php sleep(60);"), 0o644))
+
+	require.NoError(t, Init(
+		WithNumThreads(2),
+		WithScheduledTask("endless", "* * * * *", script),
+	))
+
+	require.Eventually(t, func() bool { return clock.pendingTimers() == 1 }, time.Second, time.Millisecond)
+	clock.advance(time.Minute)
+
+	require.Eventually(t, func() bool {
+		return slices.ContainsFunc(phpThreads, (*phpThread).runsScheduledTask)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	start := time.Now()
+	Shutdown()
+	assert.Less(t, time.Since(start), 30*time.Second, "the running task must be force-killed")
+}
+
+func TestNewScheduledTaskInvalid(t *testing.T) {
+	_, err := newScheduledTask("test", "* * *", filepath.Join(testDataPath, "scheduled-task.php"))
+	assert.ErrorContains(t, err, "expected 5 fields")
+
+	_, err = newScheduledTask("test", "@daily", filepath.Join(testDataPath, "unknown.php"))
+	assert.ErrorContains(t, err, "scheduled task file not found")
+}
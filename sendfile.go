@@ -0,0 +1,269 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dunglas/frankenphp/internal/fastabs"
+)
+
+// sendfile is the configuration of the X-Sendfile/X-Accel-Redirect support of a request
+type sendfile struct {
+	// name of the response header containing the path of the file, e.g. X-Accel-Redirect
+	header string
+	// directory the files are served from, the document root if empty
+	root string
+	// URI prefix => directory, relative directories are resolved from the document root
+	mappings map[string]string
+	// value of the X-Accel-Mapping request header
+	mappingHeader string
+}
+
+// WithRequestSendfile makes FrankenPHP send the file designated by the given response header
+// (usually X-Sendfile or X-Accel-Redirect) in place of the output of the script.
+//
+// The path in the header is resolved from root (the document root if empty), or from the directory
+// of the longest matching URI prefix of mappings, and can never be outside of it.
+// Absolute paths located inside root are also accepted.
+// The file is sent after the script has finished, with support for range and conditional requests.
+//
+// The X-Sendfile-Type request header (and the X-Accel-Mapping one, when using X-Accel-Redirect)
+// is set accordingly for libraries such as Symfony HttpFoundation, the ones sent by the client are discarded.
+func WithRequestSendfile(header, root string, mappings map[string]string) RequestOption {
+	s := &sendfile{
+		header:   http.CanonicalHeaderKey(header),
+		mappings: make(map[string]string, len(mappings)),
+	}
+
+	var err error
+	if root != "" {
+		s.root, err = fastabs.FastAbs(root)
+	}
+
+	parts := make([]string, 0, len(mappings))
+	for prefix, dir := range mappings {
+		s.mappings[strings.TrimSuffix(prefix, "/")] = dir
+		parts = append(parts, dir+"="+prefix)
+	}
+	slices.Sort(parts)
+	s.mappingHeader = strings.Join(parts, ",")
+
+	return func(fc *frankenPHPContext) error {
+		if err != nil {
+			return err
+		}
+
+		fc.sendfile = s
+
+		h := fc.request.Header
+		h.Set("X-Sendfile-Type", strings.ToLower(s.header))
+		// a mapping sent by the client would let it choose the files exposed by the application
+		h.Del("X-Accel-Mapping")
+		if s.header == "X-Accel-Redirect" && s.mappingHeader != "" {
+			h.Set("X-Accel-Mapping", s.mappingHeader)
+		}
+
+		return nil
+	}
+}
+
+// resolve returns the path of the file designated by the value of the header
+func (s *sendfile) resolve(value, documentRoot string) string {
+	root := s.root
+	if root == "" {
+		root = documentRoot
+	}
+
+	// X-Sendfile usually contains the absolute path of the file
+	if filepath.IsAbs(value) {
+		if rel, ok := strings.CutPrefix(filepath.Clean(value), root+separator); ok {
+			return sanitizedPathJoin(root, rel)
+		}
+	}
+
+	var prefix string
+	for p := range s.mappings {
+		if len(p) > len(prefix) && strings.HasPrefix(value, p) && (len(value) == len(p) || value[len(p)] == '/') {
+			prefix = p
+		}
+	}
+
+	if prefix == "" {
+		return sanitizedPathJoin(root, value)
+	}
+
+	dir := s.mappings[prefix]
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(documentRoot, dir)
+	}
+
+	return sanitizedPathJoin(dir, value[len(prefix):])
+}
+
+// detectSendfile checks if the script set the sendfile header, it must be called before sending the headers
+func (fc *frankenPHPContext) detectSendfile() bool {
+	h := fc.responseWriter.Header()
+
+	value := h.Get(fc.sendfile.header)
+	if value == "" {
+		return false
+	}
+
+	// never leak the path of the file to the client
+	h.Del(fc.sendfile.header)
+	fc.sendfilePath = fc.sendfile.resolve(value, fc.documentRoot)
+
+	return true
+}
+
+// serveSendfile sends the file designated by the sendfile header, the PHP thread has already been released
+func (fc *frankenPHPContext) serveSendfile() {
+	w := fc.responseWriter
+	h := w.Header()
+	// the length of the file is set by http.ServeContent
+	h.Del("Content-Length")
+
+	f, err := os.Open(fc.sendfilePath)
+	if err != nil {
+		fc.sendfileError(err)
+
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err == nil && fi.IsDir() {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		fc.sendfileError(err)
+
+		return
+	}
+
+	if h.Get("Etag") == "" {
+		h.Set("Etag", `"`+strconv.FormatInt(fi.ModTime().UnixNano(), 36)+strconv.FormatInt(fi.Size(), 36)+`"`)
+	}
+
+	r := fc.request
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		r = r.Clone(r.Context())
+		r.Method = http.MethodGet
+	}
+
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), f)
+}
+
+func (fc *frankenPHPContext) sendfileError(err error) {
+	if fc.logger.Enabled(fc.ctx, slog.LevelWarn) {
+		fc.logger.LogAttrs(fc.ctx, slog.LevelWarn, "unable to send the file", slog.String("header", fc.sendfile.header), slog.String("path", fc.sendfilePath), slog.Any("error", err))
+	}
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(fc.responseWriter, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(fc.responseWriter, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		http.Error(fc.responseWriter, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// sentFile is a file passed to frankenphp_send_file(), it is sent once the PHP thread has been released
+type sentFile struct {
+	file   *os.File
+	offset int64
+	length int64
+}
+
+// sendFile registers the file to send in place of the output of the script, a negative length means until the end of the file
+func (fc *frankenPHPContext) sendFile(path string, offset, length int64) error {
+	if fc.responseWriter == nil {
+		return errors.New("frankenphp_send_file() can only be used while handling an HTTP request")
+	}
+
+	if fc.isDone {
+		return errors.New("the request has already been finished")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	fi, err := f.Stat()
+	if err == nil && !fi.Mode().IsRegular() {
+		err = fmt.Errorf("%q is not a regular file", path)
+	}
+	if err == nil && offset > fi.Size() {
+		err = fmt.Errorf("offset %d is beyond the end of %q (%d bytes)", offset, path, fi.Size())
+	}
+	if err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	if length < 0 || offset+length > fi.Size() {
+		length = fi.Size() - offset
+	}
+
+	if fc.sentFile != nil {
+		_ = fc.sentFile.file.Close()
+	}
+	fc.sentFile = &sentFile{file: f, offset: offset, length: length}
+
+	return nil
+}
+
+// serveSentFile streams the file passed to frankenphp_send_file(), the headers have already been sent
+func (fc *frankenPHPContext) serveSentFile() {
+	// the headers of the successful response announcing the length of the file are already sent,
+	// the file must be sent even if the script has timed out since
+	if fc.request.Method == http.MethodHead {
+		return
+	}
+
+	sf := fc.sentFile
+
+	_, err := sf.file.Seek(sf.offset, io.SeekStart)
+	if err == nil {
+		// the response writer uses sendfile(2) when possible
+		_, err = io.Copy(fc.responseWriter, io.LimitReader(sf.file, sf.length))
+	}
+
+	if err != nil && fc.logger.Enabled(fc.ctx, slog.LevelDebug) {
+		fc.logger.LogAttrs(fc.ctx, slog.LevelDebug, "unable to send the file", slog.String("path", sf.file.Name()), slog.Any("error", err))
+	}
+}
+
+// outputReplaced returns true if the output of the script is replaced by a file
+func (fc *frankenPHPContext) outputReplaced() bool {
+	return fc.sendfilePath != "" || fc.sentFile != nil
+}
+
+//export go_frankenphp_send_file
+func go_frankenphp_send_file(threadIndex C.uintptr_t, path *C.char, offset C.zend_long, length C.zend_long) *C.char {
+	fc := phpThreads[threadIndex].handler.frankenPHPContext()
+	if fc == nil {
+		return C.CString("frankenphp_send_file() can only be used while handling an HTTP request")
+	}
+
+	if err := fc.sendFile(C.GoString(path), int64(offset), int64(length)); err != nil {
+		// PHP exception message.
+		return C.CString(err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package frankenphp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dunglas/frankenphp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendfile_module(t *testing.T) { testSendfile(t, &testOptions{}) }
+func TestSendfile_worker(t *testing.T) {
+	testSendfile(t, &testOptions{workerScript: "sendfile.php"})
+}
+func testSendfile(t *testing.T, opts *testOptions) {
+	opts.requestOpts = append(opts.requestOpts, frankenphp.WithRequestSendfile("X-Accel-Redirect", "", map[string]string{"/private": "files"}))
+
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, _ int) {
+		body, resp := testGet("http://example.com/sendfile.php?file=hello.txt", handler, t)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "Hello\n", body)
+		assert.Equal(t, "x-accel-redirect", resp.Header.Get("Sendfile-Type"))
+		assert.Equal(t, "files=/private", resp.Header.Get("Accel-Mapping"))
+		assert.Empty(t, resp.Header.Get("X-Accel-Redirect"))
+		assert.NotEmpty(t, resp.Header.Get("Etag"))
+
+		body, resp = testGet("http://example.com/sendfile.php?file=/private/static.txt", handler, t)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "Hello from file\n", body)
+
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/sendfile.php?file=/private/static.txt", nil)
+		req.Header.Set("Range", "bytes=0-4")
+		body, resp = testRequest(req, handler, t)
+		assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+		assert.Equal(t, "Hello", body)
+
+		// the files can never be outside of the root or mapped directories
+		_, resp = testGet("http://example.com/sendfile.php?file=/private/../../go.mod", handler, t)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		_, resp = testGet("http://example.com/sendfile.php?file=../go.mod", handler, t)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}, opts)
+}
+
+func TestSendfileIgnoresClientMapping(t *testing.T) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, _ int) {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/sendfile.php?file=hello.txt", nil)
+		req.Header.Set("X-Accel-Mapping", "/=/private")
+		_, resp := testRequest(req, handler, t)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Accel-Mapping"))
+	}, &testOptions{requestOpts: []frankenphp.RequestOption{frankenphp.WithRequestSendfile("X-Accel-Redirect", "", nil)}})
+}
+
+func TestSendFile_module(t *testing.T) { testSendFile(t, &testOptions{}) }
+func TestSendFile_worker(t *testing.T) {
+	testSendFile(t, &testOptions{workerScript: "send-file.php"})
+}
+func testSendFile(t *testing.T, opts *testOptions) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, _ int) {
+		body, resp := testGet("http://example.com/send-file.php", handler, t)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "Hello from file\n", body)
+		assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "bar", resp.Header.Get("X-Foo"))
+		assert.Equal(t, "16", resp.Header.Get("Content-Length"))
+
+		body, _ = testGet("http://example.com/send-file.php?offset=6&length=4", handler, t)
+		assert.Equal(t, "from", body)
+
+		body, _ = testGet("http://example.com/send-file.php?offset=6", handler, t)
+		assert.Equal(t, "from file\n", body)
+
+		body, _ = testGet("http://example.com/send-file.php?offset=100", handler, t)
+		assert.Contains(t, body, "is beyond the end of")
+
+		body, _ = testGet("http://example.com/send-file.php?file=missing.txt", handler, t)
+		assert.Contains(t, body, "missing.txt")
+
+		body, resp = testGet("http://example.com/send-file.php?status=404", handler, t)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "error page", body)
+		assert.NotEqual(t, "16", resp.Header.Get("Content-Length"))
+	}, opts)
+}
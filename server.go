@@ -5,7 +5,9 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/dunglas/frankenphp/internal/fastabs"
 )
@@ -28,6 +30,21 @@ type Server struct {
 	// ServeHTTP calls while Init()/Shutdown() flip it, hence atomic
 	isRegistered atomic.Bool
 	logger       *slog.Logger
+
+	// maximum number of regular threads handling requests of this server at the same time, 0 means unlimited
+	maxThreads  int
+	threadSlots chan struct{}
+	// number of threads currently handling a request of this server
+	busyThreads atomic.Int32
+
+	// number of regular threads dedicated to this server, 0 means the server only uses the shared threads
+	minThreads int
+	// dedicated regular threads and their queue, requestChan is nil if the server has no dedicated threads
+	threads     []*phpThread
+	threadMu    sync.RWMutex
+	requestChan chan *frankenPHPContext
+	// number of requests of this server waiting for a thread
+	queuedRequests atomic.Int32
 }
 
 var (
@@ -61,6 +78,17 @@ func registerServers(newServers []*Server) {
 			s.name = "server_" + strconv.Itoa(i)
 		}
 		s.resetWorkers()
+
+		s.threadSlots = nil
+		if s.maxThreads > 0 {
+			s.threadSlots = make(chan struct{}, s.maxThreads)
+		}
+
+		s.threads = nil
+		s.requestChan = nil
+		if s.minThreads > 0 {
+			s.requestChan = make(chan *frankenPHPContext)
+		}
 	}
 }
 
@@ -109,6 +137,10 @@ func NewServer(root string, options ...ServerOption) (*Server, error) {
 		}
 	}
 
+	if s.maxThreads > 0 && s.minThreads > s.maxThreads {
+		return nil, fmt.Errorf("server min_threads (%d) cannot be greater than server max_threads (%d)", s.minThreads, s.maxThreads)
+	}
+
 	if s.logger == nil {
 		s.logger = globalLogger
 	}
@@ -130,6 +162,61 @@ func (s *Server) Name() string {
 	return s.name
 }
 
+// acquireThread waits until the server is below its thread quota, returns false if max_wait_time is exceeded first
+func (s *Server) acquireThread() bool {
+	if s.threadSlots == nil {
+		return true
+	}
+
+	select {
+	case s.threadSlots <- struct{}{}:
+		return true
+	case <-timeoutChan(time.Duration(maxWaitTime.Load())):
+		return false
+	}
+}
+
+// releaseThread gives back the slot taken by acquireThread
+func (s *Server) releaseThread() {
+	if s.threadSlots != nil {
+		<-s.threadSlots
+	}
+}
+
+// startRequest is called when a thread starts handling a request of the server
+func (s *Server) startRequest() {
+	s.busyThreads.Add(1)
+	metrics.StartServerRequest(s.name)
+}
+
+// stopRequest is called when a thread is done with a request of the server
+func (s *Server) stopRequest() {
+	s.busyThreads.Add(-1)
+	metrics.StopServerRequest(s.name)
+}
+
+// isIsolated returns true if the requests of the server may only be handled by its dedicated threads
+func (s *Server) isIsolated() bool {
+	return s.minThreads > 0 && s.maxThreads > 0 && s.maxThreads <= s.minThreads
+}
+
+func (s *Server) attachThread(thread *phpThread) {
+	s.threadMu.Lock()
+	s.threads = append(s.threads, thread)
+	s.threadMu.Unlock()
+}
+
+func (s *Server) detachThread(thread *phpThread) {
+	s.threadMu.Lock()
+	for i, t := range s.threads {
+		if t == thread {
+			s.threads = append(s.threads[:i], s.threads[i+1:]...)
+			break
+		}
+	}
+	s.threadMu.Unlock()
+}
+
 func (s *Server) addWorker(w *worker) error {
 	s.workers = append(s.workers, w)
 	if w.matchRequest != nil {
@@ -167,11 +254,44 @@ func (s *Server) ServeHTTP(responseWriter http.ResponseWriter, request *http.Req
 		return err
 	}
 
+	// the connection is held by FrankenPHP, its events are sent to the worker as messages
+	if fc.worker != nil && fc.worker.websocket && isWebSocketUpgrade(request) {
+		fc.worker.serveWebSocket(responseWriter, request)
+
+		return nil
+	}
+
 	// Handle request with a worker if one is assigned
 	if fc.worker != nil {
-		return fc.worker.handleRequest(fc)
+		err = fc.worker.handleRequest(fc)
+	} else {
+		// If no worker was available, send the request to non-worker threads
+		err = handleRequestWithRegularPHPThreads(fc)
+	}
+
+	if fc.sentFile != nil {
+		defer fc.sentFile.file.Close()
+	}
+
+	// the subscriber is registered by the script, it must be released even if the events are not streamed
+	if fc.sseSubscriber != nil {
+		defer fc.sseUnsubscribe()
+	}
+
+	// the thread has been released, wait for the slow clients here
+	fc.closeDetachedOutput()
+
+	// the file is sent once the thread has been released
+	if err == nil {
+		switch {
+		case fc.sendfilePath != "":
+			fc.serveSendfile()
+		case fc.sentFile != nil:
+			fc.serveSentFile()
+		case fc.sseSubscriber != nil:
+			fc.serveSSE()
+		}
 	}
 
-	// If no worker was available, send the request to non-worker threads
-	return handleRequestWithRegularPHPThreads(fc)
+	return err
 }
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/dunglas/frankenphp"
 	"github.com/stretchr/testify/assert"
@@ -214,6 +215,88 @@ func TestServer(t *testing.T) {
 		assert.ErrorIs(t, server.ServeHTTP(nil, nil), frankenphp.ErrNotRunning)
 	})
 
+	t.Run("max_threads", func(t *testing.T) {
+		limited, _ := frankenphp.NewServer(testDataDir, frankenphp.WithServerName("limited"), frankenphp.WithServerMaxThreads(1))
+		unlimited, _ := frankenphp.NewServer(testDataDir)
+		initServers(t, frankenphp.WithServer(limited), frankenphp.WithServer(unlimited), frankenphp.WithNumThreads(3), frankenphp.WithMaxWaitTime(100*time.Millisecond))
+
+		done := make(chan string)
+		go func() {
+			w := httptest.NewRecorder()
+			_ = limited.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/busy-loop.php?ms=1000", nil))
+			done <- w.Body.String()
+		}()
+
+		require.Eventually(t, func() bool {
+			for _, s := range frankenphp.DebugState().ServerDebugStates {
+				if s.Name == "limited" {
+					return s.BusyThreads == 1 && s.MaxThreads == 1
+				}
+			}
+
+			return false
+		}, time.Second, 10*time.Millisecond)
+
+		// the quota of the server is reached, even though threads are available
+		_, resp := serverRequest(t, limited, httptest.NewRequest(http.MethodGet, "http://example.com/busy-loop.php?ms=1", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+		// other servers are not affected
+		assert.Equal(t, "done", serverGet(t, unlimited, "http://example.com/busy-loop.php?ms=1"))
+		assert.Equal(t, "done", <-done)
+	})
+
+	t.Run("min_threads", func(t *testing.T) {
+		isolated, err := frankenphp.NewServer(testDataDir, frankenphp.WithServerName("isolated"), frankenphp.WithServerMinThreads(1), frankenphp.WithServerMaxThreads(1))
+		require.NoError(t, err)
+		shared, _ := frankenphp.NewServer(testDataDir, frankenphp.WithServerName("shared"))
+		initServers(t, frankenphp.WithServer(isolated), frankenphp.WithServer(shared), frankenphp.WithNumThreads(2), frankenphp.WithMaxWaitTime(100*time.Millisecond))
+
+		serverStateIs := func(name string, busyThreads int) func() bool {
+			return func() bool {
+				for _, s := range frankenphp.DebugState().ServerDebugStates {
+					if s.Name == name {
+						return s.BusyThreads == busyThreads
+					}
+				}
+
+				return false
+			}
+		}
+
+		// the only shared thread is busy
+		sharedDone := make(chan string)
+		go func() {
+			w := httptest.NewRecorder()
+			_ = shared.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/busy-loop.php?ms=1000", nil))
+			sharedDone <- w.Body.String()
+		}()
+		require.Eventually(t, serverStateIs("shared", 1), time.Second, 10*time.Millisecond)
+
+		// the dedicated thread still serves the isolated server
+		assert.Equal(t, "done", serverGet(t, isolated, "http://example.com/busy-loop.php?ms=1"))
+
+		// the isolated server cannot use the shared threads
+		isolatedDone := make(chan string)
+		go func() {
+			w := httptest.NewRecorder()
+			_ = isolated.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/busy-loop.php?ms=500", nil))
+			isolatedDone <- w.Body.String()
+		}()
+		require.Eventually(t, serverStateIs("isolated", 1), time.Second, 10*time.Millisecond)
+
+		_, resp := serverRequest(t, isolated, httptest.NewRequest(http.MethodGet, "http://example.com/busy-loop.php?ms=1", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+		assert.Equal(t, "done", <-isolatedDone)
+		assert.Equal(t, "done", <-sharedDone)
+	})
+
+	t.Run("invalid_min_threads", func(t *testing.T) {
+		_, err := frankenphp.NewServer(testDataDir, frankenphp.WithServerMinThreads(2), frankenphp.WithServerMaxThreads(1))
+		assert.Error(t, err)
+	})
+
 	t.Run("server_logger", func(t *testing.T) {
 		logger, buf := newTestLogger(t)
 		server, _ := frankenphp.NewServer(testDataDir, frankenphp.WithServerLogger(logger))
@@ -0,0 +1,125 @@
+package frankenphp
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/dunglas/frankenphp/internal/state"
+)
+
+var (
+	// requests running longer than this are logged with their backtrace, 0 disables the slow log
+	slowlogTimeout time.Duration
+	// max time to wait for a slow thread to report its backtrace
+	slowlogBacktraceTimeout = time.Second
+)
+
+func initSlowlog(mainThread *phpMainThread) {
+	if slowlogTimeout <= 0 {
+		return
+	}
+
+	go monitorSlowRequests(mainThread.done)
+}
+
+func monitorSlowRequests(done chan struct{}) {
+	// the ticker panics on a zero interval, very short timeouts are checked every millisecond
+	ticker := time.NewTicker(max(min(slowlogTimeout/2, time.Second), time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			for _, thread := range phpThreads {
+				fc := thread.slowRequest()
+				if fc == nil {
+					continue
+				}
+
+				if fc.worker != nil {
+					metrics.SlowWorkerRequest(fc.worker.name)
+				} else {
+					metrics.SlowRequest()
+				}
+
+				go logSlowRequest(thread, fc)
+			}
+		}
+	}
+}
+
+// slowRequest returns the request running on the thread if it exceeds slowlogTimeout and was not logged yet
+func (thread *phpThread) slowRequest() *frankenPHPContext {
+	if !thread.state.Is(state.Ready) || thread.state.IsInWaitingState() {
+		return nil
+	}
+
+	fc := thread.currentRequest()
+	// the time spent in the queue is not accounted
+	if fc == nil || fc.executionStartedAt.IsZero() || time.Since(fc.executionStartedAt) < slowlogTimeout {
+		return nil
+	}
+
+	if !fc.slowlogged.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	return fc
+}
+
+// currentRequest returns the request handled by the thread, nil if there is none
+func (thread *phpThread) currentRequest() *frankenPHPContext {
+	thread.handlerMu.RLock()
+	handler := thread.handler
+	thread.handlerMu.RUnlock()
+
+	if handler == nil {
+		return nil
+	}
+
+	thread.contextMu.RLock()
+	defer thread.contextMu.RUnlock()
+
+	fc := handler.frankenPHPContext()
+	if fc == nil || fc.request == nil || fc.responseWriter == nil {
+		return nil
+	}
+
+	return fc
+}
+
+func logSlowRequest(thread *phpThread, fc *frankenPHPContext) {
+	if !fc.logger.Enabled(fc.ctx, slog.LevelWarn) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("uri", fc.requestURI),
+		slog.String("method", fc.request.Method),
+		slog.String("script", fc.scriptFilename),
+		slog.Int("thread", thread.threadIndex),
+		slog.Duration("elapsed", time.Since(fc.executionStartedAt)),
+	}
+	if fc.worker != nil {
+		attrs = append(attrs, slog.String("worker", fc.worker.name))
+	}
+
+	frames, err := thread.backtrace(slowlogBacktraceTimeout)
+	switch {
+	case err != nil:
+		attrs = append(attrs, slog.String("backtrace_error", err.Error()))
+	case thread.currentRequest() != fc:
+		// the request finished while capturing, the backtrace belongs to another request
+		attrs = append(attrs, slog.String("backtrace_error", "request finished before the backtrace was captured"))
+	default:
+		backtrace := make([]string, 0, len(frames))
+		for _, f := range frames {
+			backtrace = append(backtrace, f.String())
+		}
+		attrs = append(attrs, slog.Any("backtrace", backtrace))
+	}
+
+	fc.logger.LogAttrs(fc.ctx, slog.LevelWarn, "slow request", attrs...)
+}
@@ -0,0 +1,19 @@
+package frankenphp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonitorSlowRequestsWithTinyTimeout(t *testing.T) {
+	previous := slowlogTimeout
+	slowlogTimeout = time.Nanosecond
+	t.Cleanup(func() { slowlogTimeout = previous })
+
+	done := make(chan struct{})
+	close(done)
+
+	assert.NotPanics(t, func() { monitorSlowRequests(done) })
+}
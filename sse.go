@@ -0,0 +1,385 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// defaultSSEHistorySize is the default number of events kept per channel to be replayed to reconnecting clients
+const defaultSSEHistorySize = 100
+
+const (
+	// sseSubscriberBufferSize is the number of events a subscriber may lag behind before being disconnected
+	sseSubscriberBufferSize = 64
+	// sseHeartbeatInterval prevents proxies from closing idle streams
+	sseHeartbeatInterval = 30 * time.Second
+	// sseIdleChannelTTL is the time after which a channel without subscribers nor new events is forgotten, with its history
+	sseIdleChannelTTL = time.Hour
+	// sseEvictionInterval is the minimum time between two lookups for idle channels
+	sseEvictionInterval = time.Minute
+)
+
+var (
+	// nil until FrankenPHP is started, kept when it restarts so that reconnecting clients get the events they missed
+	sseHub *sseBroker
+
+	errSSENotAvailable = errors.New("Server-Sent Events are not available")
+)
+
+// sseEvent is a published event, already encoded in the text/event-stream format
+type sseEvent struct {
+	id      string
+	payload string
+}
+
+type sseSubscriber struct {
+	events chan sseEvent
+	// closed when the broker disconnects the subscriber, because it is too slow or FrankenPHP is shutting down
+	done chan struct{}
+}
+
+type sseChannel struct {
+	subscribers map[*sseSubscriber]struct{}
+	// oldest first
+	history []sseEvent
+	// last time an event was published or a subscriber left
+	lastActive time.Time
+}
+
+// sseBroker dispatches the events published by PHP scripts to the connections subscribed to their channel,
+// the last historySize events of each channel are kept to be replayed using the Last-Event-ID header
+type sseBroker struct {
+	mu           sync.Mutex
+	historySize  int
+	channels     map[string]*sseChannel
+	closed       bool
+	lastEviction time.Time
+	// replaced in tests
+	clock clock
+}
+
+func newSSEBroker(historySize int) *sseBroker {
+	return &sseBroker{
+		historySize: historySize,
+		channels:    make(map[string]*sseChannel),
+		clock:       realClock{},
+	}
+}
+
+// reopen accepts subscribers again after close(), the channels and their history are kept
+func (b *sseBroker) reopen(historySize int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = false
+	b.historySize = historySize
+	for _, c := range b.channels {
+		if excess := len(c.history) - max(historySize, 0); excess > 0 {
+			c.history = c.history[excess:]
+		}
+	}
+}
+
+// evictIdleChannels forgets the channels without subscribers that have been idle for longer than sseIdleChannelTTL,
+// the lookup is done at most once per sseEvictionInterval
+func (b *sseBroker) evictIdleChannels(now time.Time) {
+	if now.Sub(b.lastEviction) < sseEvictionInterval {
+		return
+	}
+	b.lastEviction = now
+
+	for name, c := range b.channels {
+		if len(c.subscribers) == 0 && now.Sub(c.lastActive) > sseIdleChannelTTL {
+			delete(b.channels, name)
+		}
+	}
+}
+
+// publish sends the event to the subscribers of the channel and returns its ID, generated if empty
+func (b *sseBroker) publish(channel, event, data, id string) (string, error) {
+	if strings.ContainsAny(event, "\r\n") {
+		return "", fmt.Errorf("the event type must not contain line breaks: %q", event)
+	}
+	if strings.ContainsAny(id, "\r\n\x00") {
+		return "", fmt.Errorf("the event ID must not contain line breaks or NUL characters: %q", id)
+	}
+	if id == "" {
+		id = rand.Text()
+	}
+
+	e := sseEvent{id: id, payload: encodeSSEEvent(id, event, data)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	b.evictIdleChannels(now)
+
+	c := b.channels[channel]
+	if c == nil {
+		c = &sseChannel{subscribers: make(map[*sseSubscriber]struct{})}
+		b.channels[channel] = c
+	}
+	c.lastActive = now
+
+	if b.historySize > 0 {
+		if len(c.history) >= b.historySize {
+			c.history = c.history[len(c.history)-b.historySize+1:]
+		}
+		c.history = append(c.history, e)
+	}
+
+	for s := range c.subscribers {
+		select {
+		case s.events <- e:
+		default:
+			// the client will reconnect and get the missed events from the history
+			delete(c.subscribers, s)
+			close(s.done)
+		}
+	}
+
+	return id, nil
+}
+
+// subscribe registers a subscriber to the channel, the events published after lastEventID are replayed
+// if it is still in the history
+func (b *sseBroker) subscribe(channel, lastEventID string) (*sseSubscriber, []sseEvent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, nil, errSSENotAvailable
+	}
+
+	now := b.clock.Now()
+	b.evictIdleChannels(now)
+
+	c := b.channels[channel]
+	if c == nil {
+		c = &sseChannel{subscribers: make(map[*sseSubscriber]struct{}), lastActive: now}
+		b.channels[channel] = c
+	}
+
+	var replay []sseEvent
+	if lastEventID != "" {
+		for i, e := range c.history {
+			if e.id == lastEventID {
+				replay = append(replay, c.history[i+1:]...)
+
+				break
+			}
+		}
+	}
+
+	s := &sseSubscriber{events: make(chan sseEvent, sseSubscriberBufferSize), done: make(chan struct{})}
+	c.subscribers[s] = struct{}{}
+
+	return s, replay, nil
+}
+
+func (b *sseBroker) unsubscribe(channel string, s *sseSubscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.channels[channel]
+	if c == nil {
+		return
+	}
+
+	if _, ok := c.subscribers[s]; ok {
+		delete(c.subscribers, s)
+		close(s.done)
+	}
+	c.lastActive = b.clock.Now()
+
+	if len(c.subscribers) == 0 && len(c.history) == 0 {
+		delete(b.channels, channel)
+	}
+}
+
+// close disconnects all subscribers and refuses new ones until reopen() is called
+func (b *sseBroker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, c := range b.channels {
+		for s := range c.subscribers {
+			delete(c.subscribers, s)
+			close(s.done)
+		}
+	}
+}
+
+func encodeSSEEvent(id, event, data string) string {
+	var sb strings.Builder
+
+	sb.WriteString("id: ")
+	sb.WriteString(id)
+	sb.WriteByte('\n')
+
+	if event != "" {
+		sb.WriteString("event: ")
+		sb.WriteString(event)
+		sb.WriteByte('\n')
+	}
+
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	for line := range strings.SplitSeq(strings.ReplaceAll(data, "\r", "\n"), "\n") {
+		sb.WriteString("data: ")
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+	sb.WriteByte('\n')
+
+	return sb.String()
+}
+
+// sseSubscribe subscribes the request to the channel right away, so that the events published before
+// the PHP thread is released are buffered, they are streamed once the thread has been released
+func (fc *frankenPHPContext) sseSubscribe(channel string) error {
+	if fc.responseWriter == nil {
+		return errors.New("frankenphp_sse_subscribe() can only be used while handling an HTTP request")
+	}
+
+	if fc.isDone {
+		return errors.New("the request has already been finished")
+	}
+
+	b := sseHub
+	if b == nil {
+		return errSSENotAvailable
+	}
+
+	s, replay, err := b.subscribe(channel, fc.request.Header.Get("Last-Event-ID"))
+	if err != nil {
+		return err
+	}
+
+	// only the last channel is streamed
+	fc.sseUnsubscribe()
+
+	fc.sseChannel = channel
+	fc.sseSubscriber = s
+	fc.sseReplay = replay
+
+	return nil
+}
+
+// sseUnsubscribe releases the subscriber of the request, if any
+func (fc *frankenPHPContext) sseUnsubscribe() {
+	if fc.sseSubscriber == nil {
+		return
+	}
+
+	sseHub.unsubscribe(fc.sseChannel, fc.sseSubscriber)
+	fc.sseSubscriber = nil
+	fc.sseReplay = nil
+}
+
+// serveSSE streams the events of the channel until the client or the broker closes the connection
+func (fc *frankenPHPContext) serveSSE() {
+	s := fc.sseSubscriber
+	w := fc.responseWriter
+	rc := http.NewResponseController(w)
+
+	for _, e := range fc.sseReplay {
+		if _, err := w.Write([]byte(e.payload)); err != nil {
+			fc.sseError(err)
+
+			return
+		}
+	}
+	// send the headers right away, the client waits for them to consider the stream open
+	if err := rc.Flush(); err != nil {
+		fc.sseError(err)
+
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		var payload string
+		select {
+		case <-fc.request.Context().Done():
+			return
+		case <-s.done:
+			return
+		case e := <-s.events:
+			payload = e.payload
+		case <-heartbeat.C:
+			payload = ":\n\n"
+		}
+
+		if _, err := w.Write([]byte(payload)); err != nil {
+			fc.sseError(err)
+
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			fc.sseError(err)
+
+			return
+		}
+	}
+}
+
+func (fc *frankenPHPContext) sseError(err error) {
+	if fc.logger.Enabled(fc.ctx, slog.LevelDebug) {
+		fc.logger.LogAttrs(fc.ctx, slog.LevelDebug, "unable to send the Server-Sent Events", slog.String("channel", fc.sseChannel), slog.Any("error", err))
+	}
+}
+
+// drainSSE disconnects the subscribers, their requests end
+func drainSSE() {
+	if sseHub != nil {
+		sseHub.close()
+	}
+}
+
+//export go_frankenphp_sse_subscribe
+func go_frankenphp_sse_subscribe(threadIndex C.uintptr_t, channel *C.zend_string) *C.char {
+	fc := phpThreads[threadIndex].handler.frankenPHPContext()
+	if fc == nil {
+		return C.CString("frankenphp_sse_subscribe() can only be used while handling an HTTP request")
+	}
+
+	if err := fc.sseSubscribe(GoString(unsafe.Pointer(channel))); err != nil {
+		// PHP exception message.
+		return C.CString(err.Error())
+	}
+
+	return nil
+}
+
+//export go_frankenphp_sse_publish
+func go_frankenphp_sse_publish(channel, event, data, id *C.zend_string) (*C.char, *C.char) {
+	if sseHub == nil {
+		return nil, C.CString(errSSENotAvailable.Error())
+	}
+
+	var goID string
+	if id != nil {
+		goID = GoString(unsafe.Pointer(id))
+	}
+
+	publishedID, err := sseHub.publish(GoString(unsafe.Pointer(channel)), GoString(unsafe.Pointer(event)), GoString(unsafe.Pointer(data)), goID)
+	if err != nil {
+		// PHP exception message.
+		return nil, C.CString(err.Error())
+	}
+
+	return C.CString(publishedID), nil
+}
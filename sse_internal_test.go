@@ -0,0 +1,149 @@
+package frankenphp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeSSEEvent(t *testing.T) {
+	assert.Equal(t, "id: 1\nevent: update\ndata: hello\n\n", encodeSSEEvent("1", "update", "hello"))
+	assert.Equal(t, "id: 2\ndata: a\ndata: b\ndata: c\n\n", encodeSSEEvent("2", "", "a\r\nb\rc"))
+}
+
+func TestSSEBrokerReplaysHistory(t *testing.T) {
+	b := newSSEBroker(2)
+
+	for _, id := range []string{"1", "2", "3"} {
+		_, err := b.publish("news", "", id, id)
+		require.NoError(t, err)
+	}
+
+	// "1" is no longer in the history
+	_, replay, err := b.subscribe("news", "1")
+	require.NoError(t, err)
+	assert.Empty(t, replay)
+
+	_, replay, err = b.subscribe("news", "2")
+	require.NoError(t, err)
+	require.Len(t, replay, 1)
+	assert.Equal(t, "3", replay[0].id)
+}
+
+func TestSSEBrokerPublish(t *testing.T) {
+	b := newSSEBroker(defaultSSEHistorySize)
+
+	s, _, err := b.subscribe("news", "")
+	require.NoError(t, err)
+
+	id, err := b.publish("news", "update", "hello", "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, id, "an ID must be generated")
+	assert.Equal(t, id, (<-s.events).id)
+
+	_, err = b.publish("news", "up\ndate", "hello", "")
+	assert.Error(t, err)
+
+	b.unsubscribe("news", s)
+	select {
+	case <-s.done:
+	default:
+		assert.Fail(t, "the subscriber must be closed")
+	}
+}
+
+func TestSSEBrokerDisconnectsSlowSubscribers(t *testing.T) {
+	b := newSSEBroker(defaultSSEHistorySize)
+
+	s, _, err := b.subscribe("news", "")
+	require.NoError(t, err)
+
+	for range sseSubscriberBufferSize + 1 {
+		_, err := b.publish("news", "", "hello", "")
+		require.NoError(t, err)
+	}
+
+	select {
+	case <-s.done:
+	default:
+		assert.Fail(t, "the slow subscriber must be disconnected")
+	}
+
+	b.close()
+	_, _, err = b.subscribe("news", "")
+	assert.ErrorIs(t, err, errSSENotAvailable)
+}
+
+func TestSSEBrokerEvictsIdleChannels(t *testing.T) {
+	c := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b := newSSEBroker(defaultSSEHistorySize)
+	b.clock = c
+
+	_, err := b.publish("idle", "", "hello", "")
+	require.NoError(t, err)
+	s, _, err := b.subscribe("watched", "")
+	require.NoError(t, err)
+
+	c.now = c.now.Add(sseIdleChannelTTL + time.Second)
+	_, err = b.publish("news", "", "hello", "")
+	require.NoError(t, err)
+
+	assert.NotContains(t, b.channels, "idle")
+	assert.Contains(t, b.channels, "watched", "channels with subscribers must be kept")
+	assert.Contains(t, b.channels, "news")
+
+	b.unsubscribe("watched", s)
+}
+
+func TestSSEBrokerReopenKeepsHistory(t *testing.T) {
+	b := newSSEBroker(3)
+
+	for _, id := range []string{"1", "2", "3"} {
+		_, err := b.publish("news", "", id, id)
+		require.NoError(t, err)
+	}
+
+	b.close()
+	b.reopen(2)
+
+	_, replay, err := b.subscribe("news", "1")
+	require.NoError(t, err)
+	assert.Empty(t, replay, "the history must be trimmed to the new size")
+
+	_, replay, err = b.subscribe("news", "2")
+	require.NoError(t, err)
+	require.Len(t, replay, 1)
+	assert.Equal(t, "3", replay[0].id)
+}
+
+func TestSSESubscribeBuffersEventsUntilStreamed(t *testing.T) {
+	previous := sseHub
+	sseHub = newSSEBroker(defaultSSEHistorySize)
+	t.Cleanup(func() { sseHub = previous })
+
+	fc := &frankenPHPContext{
+		responseWriter: httptest.NewRecorder(),
+		request:        httptest.NewRequest(http.MethodGet, "/events", nil),
+	}
+	require.NoError(t, fc.sseSubscribe("news"))
+
+	// published while the script is still running
+	id, err := sseHub.publish("news", "", "hello", "")
+	require.NoError(t, err)
+	assert.Equal(t, id, (<-fc.sseSubscriber.events).id)
+
+	// not streamed, for instance because the script failed
+	s := fc.sseSubscriber
+	fc.sseUnsubscribe()
+	assert.Nil(t, fc.sseSubscriber)
+	assert.Empty(t, sseHub.channels["news"].subscribers)
+	select {
+	case <-s.done:
+	default:
+		assert.Fail(t, "the subscriber must be closed")
+	}
+}
@@ -0,0 +1,71 @@
+package frankenphp_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readSSEEvent reads the stream until the end of the next event
+func readSSEEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+
+	var sb strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+
+		sb.WriteString(line)
+		if line == "\n" {
+			return sb.String()
+		}
+	}
+}
+
+func TestSSE_module(t *testing.T) { testSSE(t, &testOptions{}) }
+func TestSSE_worker(t *testing.T) {
+	testSSE(t, &testOptions{workerScript: "sse.php"})
+}
+func testSSE(t *testing.T, opts *testOptions) {
+	opts.realServer = true
+	opts.nbParallelRequests = 1
+
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), ts *httptest.Server, _ int) {
+		resp, err := http.Get(ts.URL + "/sse.php")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream"))
+		assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+
+		r := bufio.NewReader(resp.Body)
+		// the output written before subscribing is sent
+		assert.Equal(t, "retry: 1000\n\n", readSSEEvent(t, r))
+
+		body, _ := testGet("http://example.com/sse.php?publish=hello&id=1", handler, t)
+		assert.Equal(t, "1", body)
+		assert.Equal(t, "id: 1\nevent: update\ndata: hello\n\n", readSSEEvent(t, r))
+
+		body, _ = testGet("http://example.com/sse.php?publish=a%0Ab&id=2", handler, t)
+		assert.Equal(t, "2", body)
+		assert.Equal(t, "id: 2\nevent: update\ndata: a\ndata: b\n\n", readSSEEvent(t, r))
+
+		// reconnecting clients get the missed events
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/sse.php", nil)
+		require.NoError(t, err)
+		req.Header.Set("Last-Event-ID", "1")
+
+		resp2, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp2.Body.Close()
+
+		r2 := bufio.NewReader(resp2.Body)
+		assert.Equal(t, "retry: 1000\n\n", readSSEEvent(t, r2))
+		assert.Equal(t, "id: 2\nevent: update\ndata: a\ndata: b\n\n", readSSEEvent(t, r2))
+	}, opts)
+}
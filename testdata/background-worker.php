@@ -0,0 +1,10 @@
+<?php
+
+$file = $_SERVER['BACKGROUND_WORKER_FILE'];
+file_put_contents($file, "started\n", FILE_APPEND);
+
+while (!frankenphp_should_stop()) {
+    usleep(10_000);
+}
+
+file_put_contents($file, "stopped\n", FILE_APPEND);
@@ -0,0 +1,12 @@
+<?php
+// Keeps the VM busy (without blocking in a syscall) for the given number of milliseconds.
+
+function spin(int $milliseconds): void
+{
+    $end = hrtime(true) + $milliseconds * 1_000_000;
+    while (hrtime(true) < $end) {
+    }
+}
+
+spin((int)($_GET['ms'] ?? 1000));
+echo 'done';
@@ -0,0 +1,21 @@
+<?php
+
+$results = [
+    frankenphp_cache_get('config', 'default'),
+    frankenphp_cache_set('config', ['debug' => true, 'hosts' => ['a', 'b']], 60),
+    frankenphp_cache_get('config'),
+    frankenphp_cache_increment('hits'),
+    frankenphp_cache_increment('hits', 10),
+    frankenphp_cache_delete('config'),
+    frankenphp_cache_delete('config'),
+    frankenphp_cache_get('config'),
+];
+
+frankenphp_cache_set('name', 'Kevin');
+try {
+    frankenphp_cache_increment('name');
+} catch (RuntimeException $e) {
+    $results[] = $e->getMessage();
+}
+
+echo json_encode($results);
@@ -0,0 +1,30 @@
+<?php
+
+$published = [];
+
+while (frankenphp_handle_request(function ($message = null) use (&$published) {
+    // HTTP requests list the published messages received so far
+    if ($message === null) {
+        echo implode(',', $published);
+
+        return null;
+    }
+
+    if (isset($message['published'])) {
+        $published[] = $message['published'];
+
+        return null;
+    }
+
+    if (isset($message['forward'])) {
+        try {
+            return frankenphp_send_message('messages', ['name' => $message['forward']]);
+        } catch (RuntimeException $e) {
+            return ['greeting' => $e->getMessage()];
+        }
+    }
+
+    return ['greeting' => 'Hello ' . $message['name']];
+})) {
+    // continue handling requests
+}
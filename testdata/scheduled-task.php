@@ -0,0 +1,5 @@
+<?php
+
+file_put_contents(getenv('SCHEDULED_TASK_FILE'), implode(' ', array_slice($argv, 1))."\n", FILE_APPEND);
+
+exit((int) ($argv[2] ?? 0));
@@ -0,0 +1,26 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    $offset = isset($_GET['offset']) ? (int) $_GET['offset'] : null;
+    $length = isset($_GET['length']) ? (int) $_GET['length'] : null;
+
+    try {
+        frankenphp_send_file(__DIR__.'/files/'.($_GET['file'] ?? 'static.txt'), ['Content-Type' => 'text/plain', 'X-Foo' => 'bar'], $offset, $length);
+    } catch (\RuntimeException $e) {
+        echo $e->getMessage();
+
+        return;
+    }
+
+    if (isset($_GET['status'])) {
+        // the file is not sent with error responses
+        http_response_code((int) $_GET['status']);
+        echo 'error page';
+
+        return;
+    }
+
+    echo 'this must not be sent';
+};
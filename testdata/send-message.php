@@ -0,0 +1,16 @@
+<?php
+
+$reply = frankenphp_send_message('messages', ['name' => $_GET['name'] ?? 'world']);
+echo $reply['greeting'], "\n";
+
+$reply = frankenphp_send_message('messages', ['forward' => 'myself']);
+echo $reply['greeting'], "\n";
+
+echo frankenphp_publish('news', ['published' => $_GET['name'] ?? 'world']), "\n";
+echo frankenphp_publish('unknown-topic', 'ignored'), "\n";
+
+try {
+    frankenphp_send_message('unknown', null);
+} catch (RuntimeException $e) {
+    echo $e->getMessage(), "\n";
+}
@@ -0,0 +1,11 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    header('Sendfile-Type: ' . ($_SERVER['HTTP_X_SENDFILE_TYPE'] ?? ''));
+    header('Accel-Mapping: ' . ($_SERVER['HTTP_X_ACCEL_MAPPING'] ?? ''));
+    header('X-Accel-Redirect: ' . $_GET['file']);
+
+    echo 'this must not be sent';
+};
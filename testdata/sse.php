@@ -0,0 +1,16 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    if (isset($_GET['publish'])) {
+        echo frankenphp_sse_publish('news', 'update', $_GET['publish'], $_GET['id'] ?? null);
+
+        return;
+    }
+
+    echo "retry: 1000\n\n";
+    frankenphp_sse_subscribe('news');
+
+    echo 'this must not be sent';
+};
@@ -0,0 +1,8 @@
+<?php
+
+frankenphp_trace_span('exiting', function () {
+    echo 'exiting';
+    exit;
+});
+
+echo 'unreachable';
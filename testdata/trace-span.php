@@ -0,0 +1,13 @@
+<?php
+
+require_once __DIR__.'/_executor.php';
+
+return function () {
+    echo frankenphp_trace_span('render', fn () => 'rendered');
+    echo "\n", $_SERVER['TRACEPARENT'] ?? 'no traceparent';
+
+    try {
+        frankenphp_trace_span('failing', fn () => throw new RuntimeException('boom'));
+    } catch (RuntimeException) {
+    }
+};
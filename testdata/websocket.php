@@ -0,0 +1,33 @@
+<?php
+
+$closed = 0;
+
+while (frankenphp_handle_request(function ($event = null) use (&$closed) {
+    // regular HTTP requests report the number of closed connections
+    if ($event === null) {
+        echo "closed: $closed";
+
+        return;
+    }
+
+    switch ($event['type']) {
+        case 'open':
+            frankenphp_ws_send($event['connection'], 'welcome ' . $event['uri']);
+            break;
+
+        case 'message':
+            if ($event['data'] === 'bye') {
+                frankenphp_ws_close($event['connection']);
+                break;
+            }
+
+            frankenphp_ws_send($event['connection'], 'echo: ' . $event['data']);
+            break;
+
+        case 'close':
+            $closed++;
+            break;
+    }
+})) {
+    // continue handling events
+}
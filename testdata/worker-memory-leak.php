@@ -0,0 +1,10 @@
+<?php
+// Worker that leaks 1MB of memory per request.
+// Uses a unique instance ID per worker script execution.
+$instanceId = bin2hex(random_bytes(8));
+$leak = [];
+
+while (frankenphp_handle_request(function () use (&$leak, $instanceId) {
+    $leak[] = str_repeat('a', 1024 * 1024);
+    echo "instance:$instanceId,count:" . count($leak);
+})) {}
@@ -20,6 +20,8 @@ type regularThread struct {
 	state        *state.ThreadState
 	thread       *phpThread
 	requestCount int
+	// the server the thread is dedicated to, nil if the thread is shared by all servers
+	server *Server
 }
 
 var (
@@ -37,15 +39,25 @@ func convertToRegularThread(thread *phpThread) {
 	attachRegularThread(thread)
 }
 
+// convertToServerThread converts the thread to a regular thread only handling requests of the server
+func convertToServerThread(thread *phpThread, s *Server) {
+	thread.setHandler(&regularThread{
+		thread: thread,
+		state:  thread.state,
+		server: s,
+	})
+	s.attachThread(thread)
+}
+
 // beforeScriptExecution returns the name of the script or an empty string on shutdown
 func (handler *regularThread) beforeScriptExecution() string {
 	switch handler.state.Get() {
 	case state.TransitionRequested:
-		detachRegularThread(handler.thread)
+		handler.detach()
 		return handler.thread.transitionToNewHandler()
 
 	case state.TransitionComplete:
-		handler.thread.updateContext(false)
+		handler.thread.updateContext(false, false)
 		handler.state.Set(state.Ready)
 
 		return handler.waitForRequest()
@@ -59,7 +71,7 @@ func (handler *regularThread) beforeScriptExecution() string {
 		return handler.waitForRequest()
 
 	case state.ShuttingDown:
-		detachRegularThread(handler.thread)
+		handler.detach()
 		// signal to stop
 		return ""
 	}
@@ -67,8 +79,9 @@ func (handler *regularThread) beforeScriptExecution() string {
 	panic("unexpected state: " + handler.state.Name())
 }
 
-func (handler *regularThread) afterScriptExecution(_ int) {
+func (handler *regularThread) afterScriptExecution(exitStatus int) {
 	handler.thread.requestCount.Add(1)
+	handler.fc.exitStatus = exitStatus
 	handler.afterRequest()
 }
 
@@ -77,9 +90,23 @@ func (handler *regularThread) frankenPHPContext() *frankenPHPContext {
 }
 
 func (handler *regularThread) name() string {
+	if handler.server != nil {
+		return "Regular PHP Thread - " + handler.server.name
+	}
+
 	return "Regular PHP Thread"
 }
 
+func (handler *regularThread) detach() {
+	if handler.server != nil {
+		handler.server.detachThread(handler.thread)
+
+		return
+	}
+
+	detachRegularThread(handler.thread)
+}
+
 func (handler *regularThread) drain() {}
 
 func (handler *regularThread) waitForRequest() string {
@@ -97,28 +124,58 @@ func (handler *regularThread) waitForRequest() string {
 		}
 	}
 
+	// max_memory reached: restart the thread to release the memory it holds
+	if maxThreadMemory > 0 && handler.requestCount > 0 {
+		if memoryUsage := handler.thread.memoryUsage(); memoryUsage > maxThreadMemory {
+			if globalLogger.Enabled(globalCtx, slog.LevelInfo) {
+				globalLogger.LogAttrs(globalCtx, slog.LevelInfo, "max memory reached, restarting thread",
+					slog.Int("thread", handler.thread.threadIndex),
+					slog.Int64("memory_usage", memoryUsage),
+					slog.Int64("max_memory", maxThreadMemory),
+				)
+			}
+
+			if handler.thread.reboot() {
+				metrics.MemoryRestart()
+
+				return ""
+			}
+		}
+	}
+
 	handler.state.MarkAsWaiting(true)
 
+	requestChan := regularRequestChan
+	if handler.server != nil {
+		requestChan = handler.server.requestChan
+	}
+
 	var fc *frankenPHPContext
 
 	select {
 	case <-handler.thread.drainChan:
 		// go back to beforeScriptExecution
 		return handler.beforeScriptExecution()
-	case fc = <-regularRequestChan:
+	case fc = <-requestChan:
 	case fc = <-handler.thread.requestChan:
 	}
 
 	handler.requestCount++
+	fc.executionStartedAt = time.Now()
 	handler.thread.contextMu.Lock()
 	handler.fc = fc
 	handler.thread.contextMu.Unlock()
 	handler.state.MarkAsWaiting(false)
+	fc.startRequestTimeout(handler.thread)
+	fc.startExecuteSpan(handler.thread)
+	fc.server.startRequest()
 
 	return fc.scriptFilename
 }
 
 func (handler *regularThread) afterRequest() {
+	handler.fc.endSpans(nil)
+	handler.fc.server.stopRequest()
 	handler.fc.closeContext()
 	handler.thread.contextMu.Lock()
 	handler.fc = nil
@@ -128,52 +185,134 @@ func (handler *regularThread) afterRequest() {
 func handleRequestWithRegularPHPThreads(fc *frankenPHPContext) error {
 	metrics.StartRequest()
 
+	s := fc.server
+
+	// the server has reached its thread quota, wait for one of its requests to finish
+	if !s.acquireThread() {
+		fc.queueWait = time.Since(fc.startedAt)
+
+		fc.reject(ErrMaxWaitTimeExceeded)
+		stopRegularRequestMetrics(fc)
+
+		return ErrMaxWaitTimeExceeded
+	}
+	defer s.releaseThread()
+
 	runtime.Gosched()
 
-	if queuedRegularThreads.Load() == 0 {
-		regularThreadMu.RLock()
-		for _, thread := range regularThreads {
-			select {
-			case thread.requestChan <- fc:
-				regularThreadMu.RUnlock()
-				<-fc.done
-				metrics.StopRequest()
-
-				return nil
-			default:
-				// thread was not available
-			}
-		}
-		regularThreadMu.RUnlock()
+	// the threads dedicated to the server are tried first
+	if s.requestChan != nil && s.queuedRequests.Load() == 0 && sendToIdleThread(&s.threadMu, &s.threads, fc) {
+		<-fc.done
+		stopRegularRequestMetrics(fc)
+
+		return nil
+	}
+
+	// isolated servers never use the shared threads
+	shared := !s.isIsolated()
+
+	if shared && queuedRegularThreads.Load() == 0 && sendToIdleThread(regularThreadMu, &regularThreads, fc) {
+		<-fc.done
+		stopRegularRequestMetrics(fc)
+
+		return nil
 	}
 
 	// if no thread was available, mark the request as queued and fan it out to all threads
-	queuedRegularThreads.Add(1)
-	metrics.QueuedRequest()
+	// isolated servers have their own queue
+	queue := &queuedRegularThreads
+	if !shared {
+		queue = &s.queuedRequests
+	}
+	if limit := maxQueueLength.Load(); queue.Add(1) > limit && limit > 0 {
+		// the queue is full, shed the request
+		queue.Add(-1)
+		metrics.ShedRequest()
 
-	for {
-		select {
-		case regularRequestChan <- fc:
-			queuedRegularThreads.Add(-1)
-			metrics.DequeuedRequest()
+		fc.reject(queueFullErr)
+		stopRegularRequestMetrics(fc)
 
-			<-fc.done
-			metrics.StopRequest()
+		return queueFullErr
+	}
 
-			return nil
-		case scaleChan <- fc:
+	var sharedChan, scale chan *frankenPHPContext
+	if shared {
+		sharedChan, scale = regularRequestChan, scaleChan
+		s.queuedRequests.Add(1)
+		metrics.QueuedRequest()
+	}
+	metrics.QueuedServerRequest(s.name)
+	fc.startQueueSpan()
+
+	for {
+		select {
+		case s.requestChan <- fc:
+		case sharedChan <- fc:
+		case scale <- fc:
 			// the request has triggered scaling, continue to wait for a thread
+			continue
 		case <-timeoutChan(time.Duration(maxWaitTime.Load())):
 			// the request has timed out stalling
-			queuedRegularThreads.Add(-1)
-			metrics.DequeuedRequest()
-			metrics.StopRequest()
+			dequeueRegularRequest(s, shared)
+			fc.endQueueSpan(ErrMaxWaitTimeExceeded)
+			fc.queueWait = time.Since(fc.startedAt)
 
 			fc.reject(ErrMaxWaitTimeExceeded)
+			stopRegularRequestMetrics(fc)
 
 			return ErrMaxWaitTimeExceeded
 		}
+
+		dequeueRegularRequest(s, shared)
+		fc.endQueueSpan(nil)
+		fc.queueWait = time.Since(fc.startedAt)
+		if shared {
+			regularWaitTimes.record(fc.queueWait)
+		}
+
+		<-fc.done
+		stopRegularRequestMetrics(fc)
+
+		return nil
+	}
+}
+
+// stopRegularRequestMetrics collects a finished regular request,
+// scheduled tasks occupy a thread but are reported by their own metrics instead of the request ones
+func stopRegularRequestMetrics(fc *frankenPHPContext) {
+	if fc.isScheduledTask {
+		metrics.StopRequest()
+
+		return
+	}
+
+	metrics.StopRequestWithStats(fc.stats())
+}
+
+// sendToIdleThread hands the request to the first idle thread, returns false if all threads are busy
+func sendToIdleThread(mu *sync.RWMutex, threads *[]*phpThread, fc *frankenPHPContext) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, thread := range *threads {
+		select {
+		case thread.requestChan <- fc:
+			return true
+		default:
+			// thread was not available
+		}
+	}
+
+	return false
+}
+
+func dequeueRegularRequest(s *Server, shared bool) {
+	if shared {
+		queuedRegularThreads.Add(-1)
+		metrics.DequeuedRequest()
 	}
+	s.queuedRequests.Add(-1)
+	metrics.DequeuedServerRequest(s.name)
 }
 
 func attachRegularThread(thread *phpThread) {
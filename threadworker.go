@@ -5,12 +5,17 @@ import "C"
 import (
 	"fmt"
 	"log/slog"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
 	"github.com/dunglas/frankenphp/internal/state"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// minBackgroundRestartInterval is the minimum time between two starts of a background script exiting successfully
+const minBackgroundRestartInterval = time.Second
+
 // representation of a thread assigned to a worker script
 // executes the PHP worker script in a loop
 // implements the threadHandler interface
@@ -20,9 +25,10 @@ type workerThread struct {
 	worker                  *worker
 	dummyFrankenPHPContext  *frankenPHPContext
 	workerFrankenPHPContext *frankenPHPContext
-	isBootingScript         bool // true if the worker has not reached frankenphp_handle_request yet
-	failureCount            int  // number of consecutive startup failures
-	requestCount            int  // number of requests handled since last restart
+	isBootingScript         bool         // true if the worker has not reached frankenphp_handle_request yet
+	failureCount            atomic.Int32 // number of consecutive startup failures
+	requestCount            int          // number of requests handled since last restart
+	memoryLimitReached      bool         // true if the thread is restarting because it uses too much memory
 }
 
 func convertToWorkerThread(thread *phpThread, worker *worker) {
@@ -44,7 +50,7 @@ func (handler *workerThread) beforeScriptExecution() string {
 		handler.worker.detachThread(handler.thread)
 		return handler.thread.transitionToNewHandler()
 	case state.Ready, state.TransitionComplete:
-		handler.thread.updateContext(true)
+		handler.thread.updateContext(true, handler.worker.background)
 		if handler.worker.onThreadReady != nil {
 			handler.worker.onThreadReady(handler.thread.threadIndex)
 		}
@@ -99,9 +105,26 @@ func setupWorkerScript(handler *workerThread, worker *worker) {
 	}
 
 	handler.dummyFrankenPHPContext = fc
-	handler.isBootingScript = true
 	handler.requestCount = 0
 
+	// background scripts never reach frankenphp_handle_request(), they are ready as soon as they start
+	if worker.background {
+		handler.isBootingScript = false
+		if handler.state.Is(state.TransitionComplete) {
+			handler.state.Set(state.Ready)
+		}
+		metrics.ReadyWorker(worker.name)
+
+		if fc.logger.Enabled(fc.ctx, slog.LevelDebug) {
+			fc.logger.LogAttrs(fc.ctx, slog.LevelDebug, "starting background worker", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex))
+		}
+
+		return
+	}
+
+	handler.isBootingScript = true
+	fc.startSpan("frankenphp.worker.boot", attribute.String("frankenphp.worker", worker.name), attribute.Int("frankenphp.thread", handler.thread.threadIndex))
+
 	if fc.logger.Enabled(fc.ctx, slog.LevelDebug) {
 		fc.logger.LogAttrs(fc.ctx, slog.LevelDebug, "starting", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex))
 	}
@@ -109,20 +132,40 @@ func setupWorkerScript(handler *workerThread, worker *worker) {
 
 func tearDownWorkerScript(handler *workerThread, exitStatus int) {
 	worker := handler.worker
+	if handler.isBootingScript {
+		handler.dummyFrankenPHPContext.endSpans(errWorkerBootFailure)
+	} else {
+		handler.dummyFrankenPHPContext.endSpans(nil)
+	}
 	handler.dummyFrankenPHPContext = nil
 
 	// if the worker request is not nil, the script might have crashed
 	// make sure to close the worker request context
+	timedOut := false
 	if handler.workerFrankenPHPContext != nil {
+		timedOut = handler.workerFrankenPHPContext.timedOut.Load()
+		handler.workerFrankenPHPContext.endSpans(errScriptFailure)
+		handler.workerFrankenPHPContext.server.stopRequest()
 		handler.workerFrankenPHPContext.closeContext()
 		handler.thread.contextMu.Lock()
 		handler.workerFrankenPHPContext = nil
 		handler.thread.contextMu.Unlock()
 	}
 
+	if worker.background {
+		tearDownBackgroundScript(handler, exitStatus)
+
+		return
+	}
+
 	// on exit status 0 we just run the worker script again
 	if exitStatus == 0 && !handler.isBootingScript {
-		metrics.StopWorker(worker.name, StopReasonRestart)
+		if handler.memoryLimitReached {
+			handler.memoryLimitReached = false
+			metrics.StopWorker(worker.name, StopReasonMemoryLimit)
+		} else {
+			metrics.StopWorker(worker.name, StopReasonRestart)
+		}
 
 		if globalLogger.Enabled(globalCtx, slog.LevelDebug) {
 			globalLogger.LogAttrs(globalCtx, slog.LevelDebug, "restarting", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex), slog.Int("exit_status", exitStatus))
@@ -131,6 +174,17 @@ func tearDownWorkerScript(handler *workerThread, exitStatus int) {
 		return
 	}
 
+	// the request timeout interrupted the script, it was already logged
+	if timedOut {
+		metrics.StopWorker(worker.name, StopReasonTimeout)
+
+		if globalLogger.Enabled(globalCtx, slog.LevelDebug) {
+			globalLogger.LogAttrs(globalCtx, slog.LevelDebug, "restarting after a request timeout", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex))
+		}
+
+		return
+	}
+
 	// worker has thrown a fatal error or has not reached frankenphp_handle_request
 	if handler.isBootingScript {
 		metrics.StopWorker(worker.name, StopReasonBootFailure)
@@ -149,7 +203,7 @@ func tearDownWorkerScript(handler *workerThread, exitStatus int) {
 		return
 	}
 
-	if worker.maxConsecutiveFailures >= 0 && startupFailChan != nil && !watcherIsEnabled && handler.failureCount >= worker.maxConsecutiveFailures {
+	if worker.maxConsecutiveFailures >= 0 && startupFailChan != nil && !watcherIsEnabled && int(handler.failureCount.Load()) >= worker.maxConsecutiveFailures {
 		startupFailChan <- fmt.Errorf("too many consecutive failures: worker %s has not reached frankenphp_handle_request()", worker.fileName)
 		handler.thread.state.Set(state.ShuttingDown)
 		return
@@ -164,19 +218,75 @@ func tearDownWorkerScript(handler *workerThread, exitStatus int) {
 		// rare case where worker script has failed on a restart during normal operation
 		// this can happen if startup success depends on external resources
 		if globalLogger.Enabled(globalCtx, slog.LevelWarn) {
-			globalLogger.LogAttrs(globalCtx, slog.LevelWarn, "worker script has failed on restart", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex), slog.Int("failures", handler.failureCount))
+			globalLogger.LogAttrs(globalCtx, slog.LevelWarn, "worker script has failed on restart", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex), slog.Int("failures", int(handler.failureCount.Load())))
 		}
 	}
 
 	// wait a bit and try again (exponential backoff)
-	backoffDuration := time.Duration(handler.failureCount*handler.failureCount*100) * time.Millisecond
+	failureCount := handler.failureCount.Load()
+	backoffDuration := time.Duration(failureCount*failureCount*100) * time.Millisecond
 	if backoffDuration > time.Second {
 		backoffDuration = time.Second
 	}
-	handler.failureCount++
+	handler.failureCount.Add(1)
 	time.Sleep(backoffDuration)
 }
 
+// tearDownBackgroundScript restarts the background script, with an exponential backoff if it keeps failing
+func tearDownBackgroundScript(handler *workerThread, exitStatus int) {
+	worker := handler.worker
+
+	if exitStatus == 0 {
+		handler.failureCount.Store(0)
+		metrics.StopWorker(worker.name, StopReasonRestart)
+
+		if globalLogger.Enabled(globalCtx, slog.LevelDebug) {
+			globalLogger.LogAttrs(globalCtx, slog.LevelDebug, "restarting background worker", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex))
+		}
+
+		// a script exiting right away would keep a CPU busy restarting it
+		handler.waitBeforeRestart(minBackgroundRestartInterval - time.Since(handler.dummyFrankenPHPContext.startedAt))
+
+		return
+	}
+
+	metrics.StopWorker(worker.name, StopReasonCrash)
+
+	// stopping anyway, no need to wait
+	if !handler.state.Is(state.Ready) {
+		return
+	}
+
+	failureCount := handler.failureCount.Add(1)
+	level := slog.LevelWarn
+	if worker.maxConsecutiveFailures >= 0 && int(failureCount) >= worker.maxConsecutiveFailures {
+		level = slog.LevelError
+	}
+	if globalLogger.Enabled(globalCtx, level) {
+		globalLogger.LogAttrs(globalCtx, level, "background worker terminated unexpectedly, restarting", slog.String("worker", worker.name), slog.Int("thread", handler.thread.threadIndex), slog.Int("exit_status", exitStatus), slog.Int("failures", int(failureCount)))
+	}
+
+	// wait a bit and try again (exponential backoff)
+	backoffDuration := time.Duration(failureCount*failureCount*100) * time.Millisecond
+	if backoffDuration > time.Second {
+		backoffDuration = time.Second
+	}
+
+	handler.waitBeforeRestart(backoffDuration)
+}
+
+// waitBeforeRestart delays the restart of a background script, unless the thread is stopping
+func (handler *workerThread) waitBeforeRestart(d time.Duration) {
+	if d <= 0 || !handler.state.Is(state.Ready) {
+		return
+	}
+
+	select {
+	case <-handler.thread.drainChan:
+	case <-time.After(d):
+	}
+}
+
 // waitForWorkerRequest is called during frankenphp_handle_request in the php worker script.
 func (handler *workerThread) waitForWorkerRequest() (bool, any) {
 	// unpin any memory left over from previous requests
@@ -189,7 +299,8 @@ func (handler *workerThread) waitForWorkerRequest() (bool, any) {
 	// Clear the first dummy request created to initialize the worker
 	if handler.isBootingScript {
 		handler.isBootingScript = false
-		handler.failureCount = 0
+		handler.failureCount.Store(0)
+		handler.dummyFrankenPHPContext.endSpans(nil)
 		if !C.frankenphp_shutdown_dummy_request() {
 			panic("Not in CGI context")
 		}
@@ -213,6 +324,26 @@ func (handler *workerThread) waitForWorkerRequest() (bool, any) {
 		}
 	}
 
+	// max_memory reached: signal reboot to release the memory held by the thread
+	if limit := handler.worker.getMaxThreadMemory(); limit > 0 && handler.requestCount > 0 {
+		if memoryUsage := handler.thread.memoryUsage(); memoryUsage > limit {
+			if globalLogger.Enabled(globalCtx, slog.LevelInfo) {
+				globalLogger.LogAttrs(globalCtx, slog.LevelInfo, "max memory reached, restarting",
+					slog.String("worker", handler.worker.name),
+					slog.Int("thread", handler.thread.threadIndex),
+					slog.Int64("memory_usage", memoryUsage),
+					slog.Int64("max_memory", limit),
+				)
+			}
+
+			if handler.thread.reboot() {
+				handler.memoryLimitReached = true
+
+				return false, nil
+			}
+		}
+	}
+
 	if handler.state.Is(state.TransitionComplete) {
 		handler.state.Set(state.Ready)
 	}
@@ -232,10 +363,14 @@ func (handler *workerThread) waitForWorkerRequest() (bool, any) {
 	}
 
 	handler.requestCount++
+	fc.executionStartedAt = time.Now()
 	handler.thread.contextMu.Lock()
 	handler.workerFrankenPHPContext = fc
 	handler.thread.contextMu.Unlock()
 	handler.state.MarkAsWaiting(false)
+	fc.startRequestTimeout(handler.thread)
+	fc.startExecuteSpan(handler.thread)
+	fc.server.startRequest()
 
 	if fc.logger.Enabled(fc.ctx, slog.LevelDebug) {
 		if handler.workerFrankenPHPContext.request == nil {
@@ -280,6 +415,13 @@ func go_frankenphp_finish_worker_request(threadIndex C.uintptr_t, retval *C.zval
 	thread := phpThreads[threadIndex]
 	fc := thread.handler.frankenPHPContext()
 
+	// the request is handled: disarm its timeout before the worker script resumes,
+	// and discard a kill signal that would otherwise interrupt the worker loop
+	fc.stopRequestTimeout()
+	if !fc.timedOut.Load() {
+		C.frankenphp_clear_kill_signal()
+	}
+
 	if retval != nil {
 		r, err := GoValue[any](unsafe.Pointer(retval))
 		if err != nil && fc.logger.Enabled(fc.ctx, slog.LevelError) {
@@ -291,6 +433,8 @@ func go_frankenphp_finish_worker_request(threadIndex C.uintptr_t, retval *C.zval
 
 	thread.requestCount.Add(1)
 
+	fc.endSpans(nil)
+	fc.server.stopRequest()
 	fc.closeContext()
 	thread.contextMu.Lock()
 	thread.handler.(*workerThread).workerFrankenPHPContext = nil
@@ -312,7 +456,9 @@ func go_frankenphp_finish_php_request(threadIndex C.uintptr_t) {
 	thread := phpThreads[threadIndex]
 	fc := thread.handler.frankenPHPContext()
 
+	fc.startSpan("frankenphp.finish_request")
 	fc.closeContext()
+	fc.endSpan(nil)
 
 	if fc.logger.Enabled(fc.ctx, slog.LevelDebug) {
 		fc.logger.LogAttrs(fc.ctx, slog.LevelDebug, "request handling finished", slog.Int("thread", thread.threadIndex), slog.String("url", fc.request.RequestURI))
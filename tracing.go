@@ -0,0 +1,173 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/dunglas/frankenphp"
+
+var (
+	// nil if tracing is disabled
+	tracer trace.Tracer
+
+	traceContextPropagator = propagation.TraceContext{}
+
+	errWorkerBootFailure = errors.New("worker script has not reached frankenphp_handle_request()")
+	errScriptFailure     = errors.New("PHP script terminated unexpectedly")
+)
+
+// traceParent returns the context spans of the request are attached to.
+// If no span has been started by the HTTP server, the W3C traceparent header sent by the client is used.
+func (fc *frankenPHPContext) traceParent() context.Context {
+	ctx := fc.ctx
+	if ctx == nil {
+		ctx = globalCtx
+	}
+
+	if fc.request == nil || trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+
+	return traceContextPropagator.Extract(ctx, propagation.HeaderCarrier(fc.request.Header))
+}
+
+// traceContext returns the context of the innermost active span of the request
+func (fc *frankenPHPContext) traceContext() context.Context {
+	if len(fc.spans) == 0 {
+		return fc.traceParent()
+	}
+
+	return trace.ContextWithSpan(fc.ctx, fc.spans[len(fc.spans)-1])
+}
+
+func (fc *frankenPHPContext) requestAttributes() []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, 4)
+	if fc.worker != nil {
+		attrs = append(attrs, attribute.String("frankenphp.worker", fc.worker.name))
+	}
+	if fc.request != nil {
+		attrs = append(attrs, attribute.String("http.request.method", fc.request.Method), attribute.String("url.path", fc.request.URL.Path))
+	}
+	if fc.scriptFilename != "" {
+		attrs = append(attrs, attribute.String("frankenphp.script", fc.scriptFilename))
+	}
+
+	return attrs
+}
+
+// startQueueSpan is called when no thread is available to handle the request
+func (fc *frankenPHPContext) startQueueSpan() {
+	if tracer == nil {
+		return
+	}
+
+	_, fc.queueSpan = tracer.Start(fc.traceParent(), "frankenphp.queue", trace.WithAttributes(fc.requestAttributes()...))
+}
+
+// endQueueSpan is called when the request leaves the queue, err is set if it has been rejected
+func (fc *frankenPHPContext) endQueueSpan(err error) {
+	if fc.queueSpan == nil {
+		return
+	}
+
+	if err != nil {
+		fc.queueSpan.RecordError(err)
+		fc.queueSpan.SetStatus(codes.Error, err.Error())
+	}
+
+	fc.queueSpan.End()
+	fc.queueSpan = nil
+}
+
+// startSpan opens a child span of the innermost active span of the request
+func (fc *frankenPHPContext) startSpan(name string, attrs ...attribute.KeyValue) {
+	if tracer == nil {
+		return
+	}
+
+	_, span := tracer.Start(fc.traceContext(), name, trace.WithAttributes(attrs...))
+	fc.spans = append(fc.spans, span)
+}
+
+// endSpan closes the innermost active span of the request
+func (fc *frankenPHPContext) endSpan(err error) {
+	if len(fc.spans) == 0 {
+		return
+	}
+
+	span := fc.spans[len(fc.spans)-1]
+	fc.spans = fc.spans[:len(fc.spans)-1]
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// endSpans closes the spans left open by the script, including the execution span
+func (fc *frankenPHPContext) endSpans(err error) {
+	for len(fc.spans) > 0 {
+		fc.endSpan(err)
+	}
+}
+
+// startExecuteSpan is called when a thread starts handling the request
+func (fc *frankenPHPContext) startExecuteSpan(thread *phpThread) {
+	if tracer == nil {
+		return
+	}
+
+	fc.startSpan("frankenphp.execute", append(fc.requestAttributes(), attribute.Int("frankenphp.thread", thread.threadIndex))...)
+}
+
+// addTraceContextToServer exposes the context of the active span to PHP as $_SERVER['TRACEPARENT'] and $_SERVER['TRACESTATE']
+func addTraceContextToServer(fc *frankenPHPContext, trackVarsArray *C.zval) {
+	if len(fc.spans) == 0 {
+		return
+	}
+
+	carrier := propagation.MapCarrier{}
+	traceContextPropagator.Inject(fc.traceContext(), carrier)
+
+	if v := carrier.Get("traceparent"); v != "" {
+		C.frankenphp_register_variable_safe(toUnsafeChar("TRACEPARENT\x00"), toUnsafeChar(v), C.size_t(len(v)), trackVarsArray)
+	}
+	if v := carrier.Get("tracestate"); v != "" {
+		C.frankenphp_register_variable_safe(toUnsafeChar("TRACESTATE\x00"), toUnsafeChar(v), C.size_t(len(v)), trackVarsArray)
+	}
+}
+
+//export go_frankenphp_start_span
+func go_frankenphp_start_span(threadIndex C.uintptr_t, name *C.char, nameLen C.size_t) {
+	fc := phpThreads[threadIndex].handler.frankenPHPContext()
+	if fc == nil {
+		return
+	}
+
+	fc.startSpan(C.GoStringN(name, C.int(nameLen)))
+}
+
+//export go_frankenphp_end_span
+func go_frankenphp_end_span(threadIndex C.uintptr_t, exception *C.char, exceptionLen C.size_t) {
+	fc := phpThreads[threadIndex].handler.frankenPHPContext()
+	if fc == nil {
+		return
+	}
+
+	var err error
+	if exception != nil {
+		err = errors.New(C.GoStringN(exception, C.int(exceptionLen)))
+	}
+
+	fc.endSpan(err)
+}
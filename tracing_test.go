@@ -0,0 +1,91 @@
+package frankenphp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dunglas/frankenphp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const (
+	testTraceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testParentSpanID = "00f067aa0ba902b7"
+)
+
+func TestTracing_module(t *testing.T) {
+	testTracing(t, &testOptions{})
+}
+
+func TestTracing_worker(t *testing.T) {
+	testTracing(t, &testOptions{workerScript: "trace-span.php", nbWorkers: 1})
+}
+
+func testTracing(t *testing.T, opts *testOptions) {
+	exporter := tracetest.NewInMemoryExporter()
+	opts.nbParallelRequests = 1
+	opts.initOpts = []frankenphp.Option{frankenphp.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))}
+
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, _ int) {
+		req := httptest.NewRequest("GET", "http://example.com/trace-span.php", nil)
+		req.Header.Set("Traceparent", "00-"+testTraceID+"-"+testParentSpanID+"-01")
+		body, _ := testRequest(req, handler, t)
+
+		assert.Contains(t, body, "rendered\n00-"+testTraceID+"-")
+
+		spans := map[string]tracetest.SpanStub{}
+		for _, s := range exporter.GetSpans() {
+			spans[s.Name] = s
+		}
+
+		execute, ok := spans["frankenphp.execute"]
+		require.True(t, ok, "the script execution must be traced")
+		assert.Equal(t, testTraceID, execute.SpanContext.TraceID().String())
+		assert.Equal(t, testParentSpanID, execute.Parent.SpanID().String())
+		assert.Contains(t, body, execute.SpanContext.SpanID().String(), "$_SERVER['TRACEPARENT'] must reference the execution span")
+
+		render, ok := spans["render"]
+		require.True(t, ok, "frankenphp_trace_span() must create a span")
+		assert.Equal(t, execute.SpanContext.SpanID(), render.Parent.SpanID())
+		assert.Equal(t, codes.Unset, render.Status.Code)
+
+		failing, ok := spans["failing"]
+		require.True(t, ok)
+		assert.Equal(t, codes.Error, failing.Status.Code)
+		assert.Equal(t, "boom", failing.Status.Description)
+
+		if opts.workerScript != "" {
+			boot, ok := spans["frankenphp.worker.boot"]
+			require.True(t, ok, "the worker boot must be traced")
+			assert.Equal(t, codes.Unset, boot.Status.Code)
+		}
+	}, opts)
+}
+
+func TestTracingExit_module(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	opts := &testOptions{
+		nbParallelRequests: 1,
+		initOpts:           []frankenphp.Option{frankenphp.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))},
+	}
+
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, _ int) {
+		body, _ := testGet("http://example.com/trace-span-exit.php", handler, t)
+		assert.Equal(t, "exiting", body)
+
+		for _, s := range exporter.GetSpans() {
+			if s.Name == "exiting" {
+				assert.Equal(t, codes.Unset, s.Status.Code, "exit() must not mark the span as failed")
+
+				return
+			}
+		}
+
+		t.Fatal("the span must be ended when exit() is called")
+	}, opts)
+}
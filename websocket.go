@@ -0,0 +1,237 @@
+package frankenphp
+
+// #include "frankenphp.h"
+import "C"
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+	"unsafe"
+
+	"golang.org/x/net/http/httpguts"
+	"golang.org/x/net/websocket"
+)
+
+// wsWriteTimeout prevents a client not reading its messages from blocking the PHP threads sending them
+const wsWriteTimeout = 10 * time.Second
+
+var (
+	ErrWebSocketNotFound = errors.New("WebSocket connection not found")
+
+	wsConnectionsMu sync.RWMutex
+	wsConnections   = map[string]*wsConnection{}
+	// running connection handlers, waited for on shutdown
+	wsWG sync.WaitGroup
+	// set when FrankenPHP starts shutting down, new connections are refused
+	wsDraining atomic.Bool
+)
+
+// wsConnection is a WebSocket connection held by FrankenPHP on behalf of a worker
+type wsConnection struct {
+	id     string
+	conn   *websocket.Conn
+	worker *worker
+	// serializes the writes and their deadline
+	writeMu sync.Mutex
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") && httpguts.HeaderValuesContainsToken(r.Header["Connection"], "upgrade")
+}
+
+// serveWebSocket completes the handshake and dispatches the events of the connection until it is closed
+func (w *worker) serveWebSocket(rw http.ResponseWriter, r *http.Request) {
+	if wsDraining.Load() {
+		http.Error(rw, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+
+		return
+	}
+
+	server := w.server
+	if server == nil {
+		server = fallbackServer
+	}
+
+	// the upgrade is reported like the other requests of the worker, the duration being the one of the handshake
+	startedAt := time.Now()
+	metrics.StartWorkerRequest(w.name)
+	status := http.StatusBadRequest
+	stopRequest := sync.OnceFunc(func() {
+		metrics.StopWorkerRequestWithStats(RequestStats{Server: server.name, Worker: w.name, Status: status, Duration: time.Since(startedAt)})
+	})
+	defer stopRequest()
+
+	s := websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			if err := w.checkWebSocketOrigin(config, r); err != nil {
+				status = http.StatusForbidden
+
+				return err
+			}
+
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			status = http.StatusSwitchingProtocols
+			stopRequest()
+
+			w.handleWebSocket(conn, r)
+		},
+	}
+
+	s.ServeHTTP(rw, r)
+}
+
+// checkWebSocketOrigin prevents cross-site WebSocket hijacking: browsers always send the Origin header,
+// it must match the host of the request or one of the allowed origins.
+// Clients sending no Origin header are not browsers and are allowed.
+func (w *worker) checkWebSocketOrigin(config *websocket.Config, r *http.Request) error {
+	origin, err := websocket.Origin(config, r)
+	if err != nil {
+		return err
+	}
+
+	config.Origin = origin
+	if origin == nil || strings.EqualFold(origin.Host, r.Host) {
+		return nil
+	}
+
+	for _, allowed := range w.websocketOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin.Scheme+"://"+origin.Host) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("origin %q is not allowed to open a WebSocket connection", origin)
+}
+
+func (w *worker) handleWebSocket(conn *websocket.Conn, r *http.Request) {
+	c := &wsConnection{id: rand.Text(), conn: conn, worker: w}
+
+	wsConnectionsMu.Lock()
+	// the connection has been accepted while the others were closed
+	if wsDraining.Load() {
+		wsConnectionsMu.Unlock()
+		_ = conn.Close()
+
+		return
+	}
+	wsConnections[c.id] = c
+	wsWG.Add(1)
+	wsConnectionsMu.Unlock()
+
+	defer wsWG.Done()
+
+	headers := make(map[string]any, len(r.Header))
+	for k, v := range r.Header {
+		headers[k] = strings.Join(v, ", ")
+	}
+
+	c.dispatch(map[string]any{"type": "open", "connection": c.id, "uri": r.RequestURI, "headers": headers})
+
+	for {
+		var data string
+		if err := websocket.Message.Receive(conn, &data); err != nil {
+			break
+		}
+
+		c.dispatch(map[string]any{"type": "message", "connection": c.id, "data": data})
+	}
+
+	wsConnectionsMu.Lock()
+	delete(wsConnections, c.id)
+	wsConnectionsMu.Unlock()
+
+	_ = conn.Close()
+
+	c.dispatch(map[string]any{"type": "close", "connection": c.id})
+}
+
+// dispatch sends the event to the callback of frankenphp_handle_request() of a thread of the worker and waits for it
+func (c *wsConnection) dispatch(event map[string]any) {
+	fc := newContextFromMessage(event, nil, globalCtx, c.worker)
+
+	// the events of the connection count against the thread quota of the server
+	var err error = ErrMaxWaitTimeExceeded
+	if fc.server.acquireThread() {
+		err = c.worker.handleRequest(fc)
+		fc.server.releaseThread()
+	}
+
+	if err != nil && globalLogger.Enabled(globalCtx, slog.LevelWarn) {
+		globalLogger.LogAttrs(globalCtx, slog.LevelWarn, "unable to deliver the WebSocket event", slog.String("worker", c.worker.name), slog.String("connection", c.id), slog.Any("event", event["type"]), slog.Any("error", err))
+	}
+}
+
+// send writes a text message, or a binary one if data is not valid UTF-8
+func (c *wsConnection) send(data string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+		return err
+	}
+
+	if utf8.ValidString(data) {
+		return websocket.Message.Send(c.conn, data)
+	}
+
+	return websocket.Message.Send(c.conn, []byte(data))
+}
+
+func wsConnectionByID(id string) (*wsConnection, error) {
+	wsConnectionsMu.RLock()
+	defer wsConnectionsMu.RUnlock()
+
+	c, ok := wsConnections[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrWebSocketNotFound, id)
+	}
+
+	return c, nil
+}
+
+// drainWebSockets closes the connections and waits for their close event to be handled, it must be called before draining the threads
+func drainWebSockets() {
+	wsConnectionsMu.RLock()
+	wsDraining.Store(true)
+	for _, c := range wsConnections {
+		_ = c.conn.Close()
+	}
+	wsConnectionsMu.RUnlock()
+
+	wsWG.Wait()
+}
+
+//export go_frankenphp_ws_send
+func go_frankenphp_ws_send(id *C.zend_string, data *C.zend_string) *C.char {
+	c, err := wsConnectionByID(GoString(unsafe.Pointer(id)))
+	if err == nil {
+		err = c.send(GoString(unsafe.Pointer(data)))
+	}
+
+	if err != nil {
+		// PHP exception message.
+		return C.CString(err.Error())
+	}
+
+	return nil
+}
+
+//export go_frankenphp_ws_close
+func go_frankenphp_ws_close(id *C.zend_string) C.bool {
+	c, err := wsConnectionByID(GoString(unsafe.Pointer(id)))
+	if err != nil {
+		return false
+	}
+
+	// the close event is dispatched once the read loop stops
+	return C.bool(c.conn.Close() == nil)
+}
@@ -0,0 +1,85 @@
+package frankenphp_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dunglas/frankenphp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestWebSocket(t *testing.T) {
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), ts *httptest.Server, _ int) {
+		conn, err := websocket.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/websocket.php?room=1", "", ts.URL)
+		require.NoError(t, err)
+
+		var msg string
+		require.NoError(t, websocket.Message.Receive(conn, &msg))
+		assert.Equal(t, "welcome /websocket.php?room=1", msg)
+
+		require.NoError(t, websocket.Message.Send(conn, "hello"))
+		require.NoError(t, websocket.Message.Receive(conn, &msg))
+		assert.Equal(t, "echo: hello", msg)
+
+		// the worker closes the connection
+		require.NoError(t, websocket.Message.Send(conn, "bye"))
+		assert.ErrorIs(t, websocket.Message.Receive(conn, &msg), io.EOF)
+		_ = conn.Close()
+
+		// regular requests are still handled by the worker
+		assert.Eventually(t, func() bool {
+			body, _ := testGet("http://example.com/websocket.php", handler, t)
+
+			return body == "closed: 1"
+		}, time.Second, 10*time.Millisecond)
+	}, &testOptions{
+		realServer:         true,
+		nbParallelRequests: 1,
+		initOpts: []frankenphp.Option{
+			frankenphp.WithWorkers("websocket", testDataDir+"websocket.php", 1, frankenphp.WithWorkerWebSocket()),
+		},
+	})
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	runTest(t, func(_ func(http.ResponseWriter, *http.Request), ts *httptest.Server, _ int) {
+		url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/websocket.php"
+
+		_, err := websocket.Dial(url, "", "https://evil.example.com")
+		assert.Error(t, err, "cross-site connections must be refused")
+
+		conn, err := websocket.Dial(url, "", "https://app.example.com")
+		require.NoError(t, err)
+
+		var msg string
+		require.NoError(t, websocket.Message.Receive(conn, &msg))
+		assert.Equal(t, "welcome /websocket.php", msg)
+		_ = conn.Close()
+	}, &testOptions{
+		realServer:         true,
+		nbParallelRequests: 1,
+		initOpts: []frankenphp.Option{
+			frankenphp.WithWorkers("websocket", testDataDir+"websocket.php", 1, frankenphp.WithWorkerWebSocket("https://app.example.com")),
+		},
+	})
+}
+
+func TestWebSocketNotEnabled(t *testing.T) {
+	runTest(t, func(_ func(http.ResponseWriter, *http.Request), ts *httptest.Server, _ int) {
+		_, err := websocket.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/websocket.php", "", ts.URL)
+
+		assert.Error(t, err)
+	}, &testOptions{
+		realServer:         true,
+		nbParallelRequests: 1,
+		initOpts: []frankenphp.Option{
+			frankenphp.WithWorkers("websocket", testDataDir+"websocket.php", 1),
+		},
+	})
+}
@@ -4,10 +4,13 @@ package frankenphp
 import "C"
 import (
 	"fmt"
+	"log/slog"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -30,10 +33,25 @@ type worker struct {
 	threads                []*phpThread
 	threadMutex            sync.RWMutex
 	maxConsecutiveFailures int
+	maxQueueLength         int
+	maxThreadMemory        int64
+	restartStrategy        RestartStrategy
+	restartMu              sync.Mutex // serializes the restarts of the worker
+	topics                 []string
+	pendingMessages        chan struct{} // published messages not handled yet, nil if the worker has no topics
+	background             bool
+	websocket              bool
+	websocketOrigins       []string
 	onThreadReady          func(int)
 	onThreadShutdown       func(int)
 	queuedRequests         atomic.Int32
+	waitTimes              waitTimeRecorder
+	requestDurations       waitTimeRecorder // durations of the most recent requests handled by the worker
+	priorityQueue          *priorityQueue
 	server                 *Server
+	// scripts included by the worker script, invalidated from the opcache when the worker is restarted alone
+	includedFiles   map[string]struct{}
+	includedFilesMu sync.Mutex
 }
 
 var (
@@ -67,6 +85,11 @@ func initWorkers(opts []workerOpt) error {
 		totalThreadsToStart += w.num
 		workers = append(workers, w)
 		workersByName[w.name] = w
+		if w.background {
+			// background workers are never matched to requests
+			continue
+		}
+
 		if w.server == nil {
 			globalWorkersByPath[w.fileName] = w
 		} else if err := w.server.addWorker(w); err != nil {
@@ -123,7 +146,7 @@ func newWorker(o workerOpt) (*worker, error) {
 		o.name = absFileName
 	}
 
-	if o.server == nil {
+	if o.server == nil && !o.background {
 		if globalWorkersByPath[absFileName] != nil {
 			return nil, fmt.Errorf("two global workers cannot have the same filename: %q", absFileName)
 		}
@@ -134,6 +157,14 @@ func newWorker(o workerOpt) (*worker, error) {
 		}
 	}
 
+	if o.background && (o.matchRequest != nil || o.requestPriority != nil || o.websocket) {
+		return nil, fmt.Errorf("background worker %q cannot handle requests, it cannot have a request matcher, priorities or accept WebSockets", o.name)
+	}
+
+	if o.requestPriority == nil && len(o.priorityClasses) > 0 {
+		return nil, fmt.Errorf("worker %q has priority classes but no request priority, use WithWorkerRequestPriority()", o.name)
+	}
+
 	if workersByName[o.name] != nil {
 		return nil, fmt.Errorf("two workers cannot have the same name: %q", o.name)
 	}
@@ -164,11 +195,26 @@ func newWorker(o workerOpt) (*worker, error) {
 		requestChan:            make(chan *frankenPHPContext),
 		threads:                make([]*phpThread, 0, o.num),
 		maxConsecutiveFailures: o.maxConsecutiveFailures,
+		maxQueueLength:         o.maxQueueLength,
+		maxThreadMemory:        o.maxThreadMemory,
+		restartStrategy:        o.restartStrategy,
+		topics:                 o.topics,
+		background:             o.background,
+		websocket:              o.websocket,
+		websocketOrigins:       o.websocketOrigins,
 		onThreadReady:          o.onThreadReady,
 		onThreadShutdown:       o.onThreadShutdown,
 		server:                 o.server,
 	}
 
+	if len(o.topics) > 0 {
+		w.pendingMessages = make(chan struct{}, maxPendingMessages)
+	}
+
+	if o.requestPriority != nil {
+		w.priorityQueue = newPriorityQueue(o.requestPriority, o.priorityClasses)
+	}
+
 	w.configureMercure(&o)
 
 	w.requestOptions = append(
@@ -190,10 +236,138 @@ func newWorker(o workerOpt) (*worker, error) {
 // force-kill is armed after a grace period to wake threads parked in
 // blocking syscalls so a stuck sleep doesn't make this hang for the
 // full duration of the syscall.
+//
+// Workers using RestartStrategyRolling are rolled instead, while the other workers
+// and the regular threads are restarted at once. The opcache is then not reset, all its scripts are invalidated instead.
 func RestartWorkers() {
-	if mainThread != nil {
+	RestartWorkersWithStrategy(RestartStrategyDefault)
+}
+
+// RestartWorkersWithStrategy is like RestartWorkers(), but overrides the restart strategy of the workers
+// unless strategy is RestartStrategyDefault.
+func RestartWorkersWithStrategy(strategy RestartStrategy) {
+	if mainThread == nil {
+		return
+	}
+
+	var rolling, others []*worker
+	for _, w := range workers {
+		if strategy == RestartStrategyRolling || (strategy == RestartStrategyDefault && w.restartStrategy == RestartStrategyRolling) {
+			rolling = append(rolling, w)
+		} else {
+			others = append(others, w)
+		}
+	}
+
+	if len(rolling) == 0 {
 		mainThread.rebootAllThreads()
+
+		return
+	}
+
+	// the engine can only be rebooted as a whole, restart the threads one by one instead
+	// and invalidate the cached scripts since the opcache cannot be reset
+	requestFullOpcacheInvalidation()
+
+	var wg sync.WaitGroup
+	for _, w := range rolling {
+		wg.Go(func() {
+			w.restart(RestartStrategyRolling, false)
+		})
+	}
+	for _, w := range others {
+		wg.Go(func() {
+			w.restart(RestartStrategyAll, false)
+		})
 	}
+	regularThreadMu.RLock()
+	threads := slices.Clone(regularThreads)
+	regularThreadMu.RUnlock()
+	for _, thread := range threads {
+		wg.Go(func() {
+			thread.drainAndReboot(false)
+		})
+	}
+	wg.Wait()
+}
+
+// RestartWorker restarts the threads of the worker with the given name, other workers keep serving requests.
+// Each thread is restarted once it has finished its current request, blocks until all threads are restarted.
+// Unlike RestartWorkers(), the opcache is not reset, only the scripts included by the worker are invalidated.
+func RestartWorker(name string) error {
+	return RestartWorkerWithStrategy(name, RestartStrategyDefault, false)
+}
+
+// ForceRestartWorker is like RestartWorker(), but threads still handling a request
+// after the grace period are force-killed.
+func ForceRestartWorker(name string) error {
+	return RestartWorkerWithStrategy(name, RestartStrategyDefault, true)
+}
+
+// RestartWorkerWithStrategy is like RestartWorker(), but overrides the restart strategy of the worker
+// unless strategy is RestartStrategyDefault. If force is true, threads still handling a request
+// after the grace period are force-killed.
+func RestartWorkerWithStrategy(name string, strategy RestartStrategy, force bool) error {
+	w, ok := workersByName[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrWorkerNotFound, name)
+	}
+
+	// with opcache.validate_timestamps=0, the restarted threads would otherwise execute the cached scripts again
+	requestOpcacheInvalidation(w.getIncludedFiles())
+	w.restart(strategy, force)
+
+	return nil
+}
+
+func (worker *worker) restart(strategy RestartStrategy, force bool) {
+	if strategy == RestartStrategyDefault {
+		strategy = worker.restartStrategy
+	}
+
+	// scalingMu is not held while waiting for the threads: the state of a rebooting thread
+	// already prevents it from being converted by autoscaling or SetMaxThreads()
+	worker.restartMu.Lock()
+	defer worker.restartMu.Unlock()
+
+	worker.threadMutex.RLock()
+	threads := slices.Clone(worker.threads)
+	worker.threadMutex.RUnlock()
+
+	if globalLogger.Enabled(globalCtx, slog.LevelInfo) {
+		globalLogger.LogAttrs(globalCtx, slog.LevelInfo, "restarting worker", slog.String("worker", worker.name), slog.Int("num_threads", len(threads)), slog.String("strategy", strategy.String()), slog.Bool("force", force))
+	}
+
+	if strategy == RestartStrategyRolling {
+		worker.restartRolling(threads, force)
+
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, thread := range threads {
+		wg.Go(func() {
+			thread.drainAndReboot(force)
+		})
+	}
+	wg.Wait()
+}
+
+func (worker *worker) addIncludedFile(file string) {
+	worker.includedFilesMu.Lock()
+	defer worker.includedFilesMu.Unlock()
+
+	if worker.includedFiles == nil {
+		worker.includedFiles = make(map[string]struct{})
+	}
+	worker.includedFiles[file] = struct{}{}
+}
+
+func (worker *worker) getIncludedFiles() []string {
+	worker.includedFilesMu.Lock()
+	defer worker.includedFilesMu.Unlock()
+
+	return slices.Collect(maps.Keys(worker.includedFiles))
 }
 
 func (worker *worker) attachThread(thread *phpThread) {
@@ -234,6 +408,24 @@ func (worker *worker) isAtThreadLimit() bool {
 	return atMaxThreads
 }
 
+// getMaxQueueLength returns the max number of queued requests for this worker, 0 means unlimited
+func (worker *worker) getMaxQueueLength() int32 {
+	if worker.maxQueueLength > 0 {
+		return int32(worker.maxQueueLength)
+	}
+
+	return maxQueueLength.Load()
+}
+
+// getMaxThreadMemory returns the memory usage above which a thread of this worker is restarted, 0 means unlimited
+func (worker *worker) getMaxThreadMemory() int64 {
+	if worker.maxThreadMemory > 0 {
+		return worker.maxThreadMemory
+	}
+
+	return maxThreadMemory
+}
+
 func (worker *worker) handleRequest(fc *frankenPHPContext) error {
 	metrics.StartWorkerRequest(worker.name)
 
@@ -246,8 +438,7 @@ func (worker *worker) handleRequest(fc *frankenPHPContext) error {
 			select {
 			case thread.requestChan <- fc:
 				worker.threadMutex.RUnlock()
-				<-fc.done
-				metrics.StopWorkerRequest(worker.name, time.Since(fc.startedAt))
+				worker.awaitRequest(fc)
 
 				return nil
 			default:
@@ -258,9 +449,49 @@ func (worker *worker) handleRequest(fc *frankenPHPContext) error {
 	}
 
 	// if no thread was available, mark the request as queued and apply the scaling strategy
-	worker.queuedRequests.Add(1)
+	if limit := worker.getMaxQueueLength(); worker.queuedRequests.Add(1) > limit && limit > 0 {
+		// the queue is full, shed the request
+		worker.queuedRequests.Add(-1)
+		metrics.ShedWorkerRequest(worker.name)
+
+		fc.reject(queueFullErr)
+		metrics.StopWorkerRequestWithStats(fc.stats())
+
+		return queueFullErr
+	}
 	metrics.QueuedWorkerRequest(worker.name)
+	fc.startQueueSpan()
+
+	if worker.priorityQueue != nil {
+		return worker.handlePrioritizedRequest(fc)
+	}
+
+	return worker.waitForThread(fc, timeoutChan(fc.getMaxWaitTime(time.Duration(maxWaitTime.Load()))), nil)
+}
+
+// handlePrioritizedRequest waits for the turn of a queued request before competing for a thread
+func (worker *worker) handlePrioritizedRequest(fc *frankenPHPContext) error {
+	qr := worker.priorityQueue.push(fc)
+	timeout := timeoutChan(fc.getMaxWaitTime(qr.class.getMaxWaitTime()))
+
+	select {
+	case <-qr.turn:
+	case <-timeout:
+		// the request has timed out waiting for its turn
+		if !worker.priorityQueue.remove(qr) {
+			// the turn was given to the request concurrently, pass it on
+			worker.priorityQueue.release(qr)
+		}
+
+		return worker.rejectStalledRequest(fc)
+	}
+
+	return worker.waitForThread(fc, timeout, func() { worker.priorityQueue.release(qr) })
+}
 
+// waitForThread hands a queued request to the first available thread, scaling if needed, until the timeout.
+// leaveQueue, if any, is called as soon as the request leaves the queue.
+func (worker *worker) waitForThread(fc *frankenPHPContext, timeout <-chan time.Time, leaveQueue func()) error {
 	for {
 		workerScaleChan := scaleChan
 		if worker.isAtThreadLimit() {
@@ -269,23 +500,48 @@ func (worker *worker) handleRequest(fc *frankenPHPContext) error {
 
 		select {
 		case worker.requestChan <- fc:
-			worker.queuedRequests.Add(-1)
-			metrics.DequeuedWorkerRequest(worker.name)
-			<-fc.done
-			metrics.StopWorkerRequest(worker.name, time.Since(fc.startedAt))
+			if leaveQueue != nil {
+				leaveQueue()
+			}
+			worker.dequeueRequest(fc, nil)
+			worker.waitTimes.record(fc.queueWait)
+			worker.awaitRequest(fc)
 
 			return nil
 		case workerScaleChan <- fc:
 			// the request has triggered scaling, continue to wait for a thread
-		case <-timeoutChan(time.Duration(maxWaitTime.Load())):
+		case <-timeout:
 			// the request has timed out stalling
-			worker.queuedRequests.Add(-1)
-			metrics.DequeuedWorkerRequest(worker.name)
-			metrics.StopWorkerRequest(worker.name, time.Since(fc.startedAt))
-
-			fc.reject(ErrMaxWaitTimeExceeded)
+			if leaveQueue != nil {
+				leaveQueue()
+			}
 
-			return ErrMaxWaitTimeExceeded
+			return worker.rejectStalledRequest(fc)
 		}
 	}
 }
+
+// dequeueRequest accounts for a request leaving the queue, err is set if it did not get a thread
+func (worker *worker) dequeueRequest(fc *frankenPHPContext, err error) {
+	worker.queuedRequests.Add(-1)
+	metrics.DequeuedWorkerRequest(worker.name)
+	fc.endQueueSpan(err)
+	fc.queueWait = time.Since(fc.startedAt)
+}
+
+// rejectStalledRequest rejects a queued request that exceeded its max wait time
+func (worker *worker) rejectStalledRequest(fc *frankenPHPContext) error {
+	worker.dequeueRequest(fc, ErrMaxWaitTimeExceeded)
+
+	fc.reject(ErrMaxWaitTimeExceeded)
+	metrics.StopWorkerRequestWithStats(fc.stats())
+
+	return ErrMaxWaitTimeExceeded
+}
+
+// awaitRequest waits for the thread handling the request to finish it
+func (worker *worker) awaitRequest(fc *frankenPHPContext) {
+	<-fc.done
+	worker.requestDurations.record(time.Since(fc.startedAt))
+	metrics.StopWorkerRequestWithStats(fc.stats())
+}
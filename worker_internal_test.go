@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/dunglas/frankenphp/internal/state"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -76,3 +77,51 @@ func TestRestartWorkersForceKillsStuckThread(t *testing.T) {
 	assert.NotContains(t, recorder.Body.String(), "should not reach",
 		"VM interrupt was never observed; sleep returned naturally")
 }
+
+func TestRollingRestartWorker(t *testing.T) {
+	cwd, _ := os.Getwd()
+	testDataDir := cwd + "/testdata/"
+
+	require.NoError(t, Init(
+		WithWorkers("counter", testDataDir+"worker-with-counter.php", 2, WithWorkerRestartStrategy(RestartStrategyRolling)),
+		WithNumThreads(3),
+		WithMaxThreads(4),
+	))
+	t.Cleanup(Shutdown)
+
+	serve := func() string {
+		req := httptest.NewRequest("GET", "http://example.com/worker-with-counter.php", nil)
+		fr, err := NewRequestWithContext(req, WithRequestDocumentRoot(testDataDir, false))
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		require.NoError(t, ServeHTTP(recorder, fr))
+
+		return recorder.Body.String()
+	}
+
+	for range 4 {
+		assert.Contains(t, serve(), "requests:")
+	}
+
+	restarted := make(chan struct{})
+	go func() {
+		defer close(restarted)
+		assert.NoError(t, RestartWorker("counter"))
+	}()
+
+	// requests must keep being served while the threads restart
+	for {
+		select {
+		case <-restarted:
+			assert.Contains(t, serve(), "requests:")
+			assert.Equal(t, 2, workersByName["counter"].countThreads(), "the extra thread must be removed after the restart")
+			assert.Eventually(t, func() bool {
+				return phpThreads[3].state.Is(state.Inactive)
+			}, time.Second, 10*time.Millisecond)
+
+			return
+		default:
+			assert.Contains(t, serve(), "requests:")
+		}
+	}
+}
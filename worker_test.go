@@ -9,13 +9,17 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/dunglas/frankenphp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestWorker(t *testing.T) {
@@ -230,6 +234,38 @@ func TestWorkerMaxRequests(t *testing.T) {
 	})
 }
 
+// TestWorkerMaxThreadMemory verifies that a leaking worker restarts once its memory exceeds max_memory.
+func TestWorkerMaxThreadMemory(t *testing.T) {
+	const totalRequests = 20
+
+	var buf syncBuffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	runTest(t, func(handler func(http.ResponseWriter, *http.Request), _ *httptest.Server, _ int) {
+		instanceIDs := make(map[string]int)
+
+		for i := 0; i < totalRequests; i++ {
+			body, resp := testGet("http://example.com/worker-memory-leak.php", handler, t)
+			assert.Equal(t, 200, resp.StatusCode)
+
+			parts := strings.Split(body, ",")
+			if len(parts) == 2 {
+				instanceIDs[strings.TrimPrefix(parts[0], "instance:")]++
+			}
+		}
+
+		// each request leaks 1MB, a thread must restart after a few requests
+		assert.GreaterOrEqual(t, len(instanceIDs), 2)
+		assert.GreaterOrEqual(t, strings.Count(buf.String(), "max memory reached, restarting"), 2)
+	}, &testOptions{
+		workerScript:       "worker-memory-leak.php",
+		nbWorkers:          1,
+		nbParallelRequests: 1,
+		logger:             logger,
+		initOpts:           []frankenphp.Option{frankenphp.WithNumThreads(2), frankenphp.WithMaxThreadMemory(8 << 20)},
+	})
+}
+
 // TestWorkerMaxRequestsHighConcurrency verifies max_requests works under concurrent load.
 func TestWorkerMaxRequestsHighConcurrency(t *testing.T) {
 	const maxRequests = 10
@@ -274,3 +310,80 @@ func TestWorkerMaxRequestsHighConcurrency(t *testing.T) {
 		initOpts:           []frankenphp.Option{frankenphp.WithNumThreads(5), frankenphp.WithMaxRequests(maxRequests)},
 	})
 }
+
+func TestBackgroundWorker(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "background-worker.log")
+	readFile := func() string {
+		b, _ := os.ReadFile(file)
+
+		return string(b)
+	}
+
+	require.NoError(t, frankenphp.Init(
+		frankenphp.WithWorkers("background", testDataDir+"background-worker.php", 1,
+			frankenphp.WithWorkerBackground(),
+			frankenphp.WithWorkerEnv(map[string]string{"BACKGROUND_WORKER_FILE": file}),
+		),
+		frankenphp.WithNumThreads(2),
+	))
+	t.Cleanup(frankenphp.Shutdown)
+
+	assert.Eventually(t, func() bool { return readFile() == "started\n" }, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, frankenphp.RestartWorker("background"))
+	assert.Eventually(t, func() bool { return readFile() == "started\nstopped\nstarted\n" }, 5*time.Second, 10*time.Millisecond)
+
+	frankenphp.Shutdown()
+	assert.Equal(t, "started\nstopped\nstarted\nstopped\n", readFile())
+}
+
+func TestRestartWorkerInvalidatesOpcache(t *testing.T) {
+	testRestartInvalidatesOpcache(t, func() {
+		require.NoError(t, frankenphp.RestartWorker("versioned"))
+	})
+}
+
+func TestRollingRestartInvalidatesOpcache(t *testing.T) {
+	testRestartInvalidatesOpcache(t, func() {
+		frankenphp.RestartWorkersWithStrategy(frankenphp.RestartStrategyRolling)
+	})
+}
+
+func testRestartInvalidatesOpcache(t *testing.T, restart func()) {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeVersion := func(version string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "version.php"), []byte("<?php return '"+version+"';"), 0o644))
+	}
+	writeVersion("v1")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "worker.php"), []byte(`<?php
+while (frankenphp_handle_request(function () { echo require __DIR__ . '/version.php'; })) {
+}
+`), 0o644))
+
+	require.NoError(t, frankenphp.Init(
+		frankenphp.WithWorkers("versioned", filepath.Join(dir, "worker.php"), 1),
+		frankenphp.WithNumThreads(2),
+		frankenphp.WithPhpIni(map[string]string{"opcache.enable": "1", "opcache.validate_timestamps": "0"}),
+	))
+	t.Cleanup(frankenphp.Shutdown)
+
+	get := func() string {
+		req, err := frankenphp.NewRequestWithContext(httptest.NewRequest(http.MethodGet, "http://example.com/worker.php", nil), frankenphp.WithRequestDocumentRoot(dir, false))
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		require.NoError(t, frankenphp.ServeHTTP(w, req))
+
+		return w.Body.String()
+	}
+
+	assert.Equal(t, "v1", get())
+
+	writeVersion("v2")
+	assert.Equal(t, "v1", get(), "the cached script must still be used before the restart")
+
+	restart()
+	assert.Equal(t, "v2", get())
+}